@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/xishang0128/sysproxy-go/sysproxy"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditFile       string
+	auditMaxSize    int
+	auditBackups    int
+	auditSince      string
+	auditUntil      string
+	auditOperations []string
+	auditLines      int
+	auditFollow     bool
+
+	auditLog *sysproxy.AuditLog
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "查看代理设置的审计日志",
+}
+
+var auditTailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "输出最近的审计记录，以 JSON 行输出",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter, err := auditFilter()
+		if err != nil {
+			return err
+		}
+		records, err := sysproxy.ReadAuditLog(auditPath(), filter)
+		if err != nil {
+			return fail("读取审计日志失败", err)
+		}
+		if auditLines >= 0 && len(records) > auditLines {
+			records = records[len(records)-auditLines:]
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		for _, r := range records {
+			if err := encoder.Encode(r); err != nil {
+				return fail("输出审计记录失败", err)
+			}
+		}
+		count := len(records)
+		if auditFollow {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			n, err := followAudit(ctx, auditPath(), filter, encoder)
+			count += n
+			if err != nil {
+				return fail("读取审计日志失败", err)
+			}
+		}
+		return done(cmd, "", map[string]int{"records": count})
+	},
+}
+
+var auditQueryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "按时间与操作查询审计记录，以 JSON 行输出",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter, err := auditFilter()
+		if err != nil {
+			return err
+		}
+		records, err := sysproxy.ReadAuditLog(auditPath(), filter)
+		if err != nil {
+			return fail("读取审计日志失败", err)
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		for _, r := range records {
+			if err := encoder.Encode(r); err != nil {
+				return fail("输出审计记录失败", err)
+			}
+		}
+		return done(cmd, "", map[string]int{"records": len(records)})
+	},
+}
+
+// setupAudit 在指定 --audit-log 时打开审计日志，服务与修改设置的命令会写入记录
+func setupAudit() error {
+	if auditFile == "" {
+		return nil
+	}
+	if auditMaxSize < 0 || auditBackups < 0 {
+		return invalid("审计日志的大小与保留数量不能为负数")
+	}
+	auditLog = &sysproxy.AuditLog{
+		Path:       auditFile,
+		MaxSize:    int64(auditMaxSize) << 20,
+		MaxBackups: auditBackups,
+	}
+	sysproxy.SetAuditLog(auditLog)
+	return nil
+}
+
+// audited 执行修改设置的命令并记录耗时，开启审计日志时记录修改前后的设置
+func audited(operation string, change func() error) error {
+	return measure(func() error {
+		return audit(operation, change)
+	})
+}
+
+// audit 执行修改，开启审计日志时记录修改前后的设置
+func audit(operation string, change func() error) error {
+	if auditLog == nil {
+		return change()
+	}
+	before, _ := sysproxy.QueryProxySettings(device, onlyActiveDevice)
+	start := time.Now()
+	err := change()
+	record := sysproxy.AuditRecord{
+		Time:      start,
+		Source:    sysproxy.AuditSourceCLI,
+		Operation: operation,
+		Device:    device,
+		Callers:   []sysproxy.AuditCaller{{PeerCred: selfCred()}},
+		Before:    before,
+		Duration:  time.Since(start),
+		Result:    "ok",
+	}
+	if err != nil {
+		record.Result = string(sysproxy.CodeOf(err))
+		if record.Result == "" {
+			record.Result = "error"
+		}
+		record.Error = err.Error()
+	}
+	record.After, _ = sysproxy.QueryProxySettings(device, onlyActiveDevice)
+	if err := auditLog.Write(record); err != nil {
+		slog.Warn("write audit log failed", "path", auditLog.Path, "error", err)
+	}
+	return err
+}
+
+// auditedBackend 修改系统的设置并记录到审计日志，用于保持、看门狗与自动切换在后台进行的修改，
+// apply 与 restore 为记录的操作名
+type auditedBackend struct {
+	apply   string
+	restore string
+}
+
+func (auditedBackend) Query(device string, onlyActiveDevice bool) (*sysproxy.ProxyConfig, error) {
+	return sysproxy.QueryProxySettings(device, onlyActiveDevice)
+}
+
+func (b auditedBackend) Apply(config *sysproxy.ProxyConfig, device string, onlyActiveDevice bool) error {
+	return audit(b.apply, func() error {
+		return sysproxy.Apply(config, device, onlyActiveDevice)
+	})
+}
+
+func (b auditedBackend) Restore(config *sysproxy.ProxyConfig, device string, onlyActiveDevice bool) error {
+	return audit(b.restore, func() error {
+		return sysproxy.Restore(config, device, onlyActiveDevice)
+	})
+}
+
+func selfCred() *sysproxy.PeerCred {
+	exe, _ := os.Executable()
+	return &sysproxy.PeerCred{PID: os.Getpid(), UID: os.Getuid(), GID: os.Getgid(), Exe: exe}
+}
+
+func auditPath() string {
+	if auditFile != "" {
+		return auditFile
+	}
+	return sysproxy.DefaultAuditPath()
+}
+
+func auditFilter() (sysproxy.AuditFilter, error) {
+	filter := sysproxy.AuditFilter{Operations: auditOperations}
+	for _, t := range []struct {
+		value string
+		dst   *time.Time
+	}{{auditSince, &filter.Since}, {auditUntil, &filter.Until}} {
+		if t.value == "" {
+			continue
+		}
+		v, err := parseAuditTime(t.value)
+		if err != nil {
+			return filter, invalid("无效的时间：%s", t.value)
+		}
+		*t.dst = v
+	}
+	return filter, nil
+}
+
+// parseAuditTime 解析 RFC 3339 时间、本地日期时间，或表示多久以前的时长如 1h
+func parseAuditTime(value string) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{time.DateTime, "2006-01-02 15:04", time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New(value)
+}
+
+// followAudit 持续输出新写入的记录，日志轮转后从新文件开头继续读取
+func followAudit(ctx context.Context, path string, filter sysproxy.AuditFilter, encoder *json.Encoder) (int, error) {
+	f, err := os.Open(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, err
+	}
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+
+	var reader *bufio.Reader
+	if f != nil {
+		if _, err := f.Seek(0, io.SeekEnd); err != nil {
+			return 0, err
+		}
+		reader = bufio.NewReader(f)
+	}
+	count := 0
+	var partial []byte
+	// drain 输出已写入的完整行，未写完的行留到下次读取
+	drain := func() error {
+		for reader != nil {
+			line, err := reader.ReadBytes('\n')
+			partial = append(partial, line...)
+			if err != nil {
+				return nil
+			}
+			var r sysproxy.AuditRecord
+			if json.Unmarshal(partial, &r) == nil && filter.Match(r) {
+				if err := encoder.Encode(r); err != nil {
+					return err
+				}
+				count++
+			}
+			partial = partial[:0]
+		}
+		return nil
+	}
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if err := drain(); err != nil {
+			return count, err
+		}
+		select {
+		case <-ctx.Done():
+			return count, nil
+		case <-ticker.C:
+		}
+
+		// 文件被轮转或重新创建时，读完旧文件后从新文件开头继续
+		disk, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if f != nil {
+			if current, err := f.Stat(); err == nil && os.SameFile(current, disk) {
+				continue
+			}
+			if err := drain(); err != nil {
+				return count, err
+			}
+			f.Close()
+		}
+		if f, err = os.Open(path); err != nil {
+			f = nil
+			reader = nil
+			continue
+		}
+		reader = bufio.NewReader(f)
+		partial = partial[:0]
+	}
+}
+
+func init() {
+	cmd.AddCommand(auditCmd)
+	auditCmd.AddCommand(auditTailCmd)
+	auditCmd.AddCommand(auditQueryCmd)
+
+	cmd.PersistentFlags().StringVar(&auditFile, "audit-log", "", "将修改代理设置的操作记录到审计日志，仅指定 --audit-log 时使用默认路径")
+	cmd.PersistentFlags().Lookup("audit-log").NoOptDefVal = sysproxy.DefaultAuditPath()
+	cmd.PersistentFlags().IntVar(&auditMaxSize, "audit-max-size", 10, "审计日志轮转的大小（MiB）")
+	cmd.PersistentFlags().IntVar(&auditBackups, "audit-backups", 3, "保留的已轮转审计日志数量")
+
+	for _, c := range []*cobra.Command{auditTailCmd, auditQueryCmd} {
+		c.Flags().StringVar(&auditSince, "since", "", "起始时间，如 2006-01-02 15:04、RFC 3339 时间或 1h（1 小时前）")
+		c.Flags().StringVar(&auditUntil, "until", "", "结束时间，格式同 --since")
+		c.Flags().StringSliceVar(&auditOperations, "operation", nil, "只输出指定的操作，如 proxy、pac、disable、guard_start，可重复指定")
+	}
+	auditTailCmd.Flags().IntVarP(&auditLines, "lines", "n", 10, "输出的记录数")
+	auditTailCmd.Flags().BoolVarP(&auditFollow, "follow", "f", false, "持续输出新的记录")
+}
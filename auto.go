@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/xishang0128/sysproxy-go/i18n"
+	"github.com/xishang0128/sysproxy-go/sysproxy"
+
+	"github.com/spf13/cobra"
+)
+
+var autoInterval time.Duration
+
+var autoCmd = &cobra.Command{
+	Use:   "auto",
+	Short: "网络变化时按规则自动切换代理配置",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := sysproxy.LoadProfiles(profilesPath)
+		if err != nil {
+			return fail("读取配置失败", err)
+		}
+		if len(file.Rules) == 0 && file.Default == "" {
+			return invalid("配置文件中没有规则")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		auto := &sysproxy.AutoSwitch{
+			Profiles:         file,
+			Device:           device,
+			OnlyActiveDevice: onlyActiveDevice,
+			Interval:         autoInterval,
+			Backend:          auditedBackend{apply: "auto", restore: "auto"},
+			OnSwitch: func(profile string, info *sysproxy.NetworkInfo, err error) {
+				event := map[string]any{"profile": profile, "network": info}
+				if err != nil {
+					event["error"] = err.Error()
+					emit(i18n.Sprintf("切换到配置 %s 失败：%v", profile, err), event)
+					return
+				}
+				emit(i18n.Sprintf("网络已变化，切换到配置：%s", profile), event)
+			},
+		}
+		if err := auto.Run(ctx); err != nil {
+			return fail("自动切换失败", err)
+		}
+		return done(cmd, "", nil)
+	},
+}
+
+var networkCmd = &cobra.Command{
+	Use:   "network",
+	Short: "查看当前网络环境及匹配的配置",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		info, err := sysproxy.QueryNetwork()
+		if err != nil {
+			return fail("查询网络环境失败", err)
+		}
+
+		profile := ""
+		if file, err := sysproxy.LoadProfiles(profilesPath); err == nil {
+			profile = file.Select(info)
+		}
+		result := struct {
+			*sysproxy.NetworkInfo
+			Profile string `json:"profile,omitempty"`
+		}{info, profile}
+		if jsonOutput() {
+			return done(cmd, "", result)
+		}
+		networkJSON, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fail("格式化 JSON 失败", err)
+		}
+		return done(cmd, string(networkJSON), nil)
+	},
+}
+
+func init() {
+	cmd.AddCommand(autoCmd)
+	cmd.AddCommand(networkCmd)
+
+	autoCmd.Flags().DurationVarP(&autoInterval, "interval", "i", 5*time.Second, "无法订阅网络变化时的轮询间隔，以及应用失败后重试的间隔")
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/xishang0128/sysproxy-go/i18n"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// 配置的优先级为：命令行参数 > 环境变量 > 配置文件 > 默认值
+
+var configPath string
+
+// flagKeys 为与名称不同的配置项，其余参数的配置项与参数名相同
+var flagKeys = map[string]string{
+	"url": "pac-url",
+}
+
+type configFile struct {
+	values   map[string]any
+	commands map[string]map[string]any
+}
+
+var loadedConfig *configFile
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "管理默认配置",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "查看各参数的生效值及其来源",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, source := resolveConfigPath()
+		type entry struct {
+			Command string `json:"command"`
+			Flag    string `json:"flag"`
+			Value   string `json:"value"`
+			Source  string `json:"source"`
+		}
+		var entries []entry
+		visitCommands(cmd.Root(), func(c *cobra.Command, f *pflag.Flag) {
+			value, source, _ := resolveFlag(c, f)
+			entries = append(entries, entry{i18n.T(commandName(c)), f.Name, value, source})
+		})
+
+		if jsonOutput() {
+			return done(cmd, "", map[string]any{
+				"config":        path,
+				"config_source": i18n.T(source),
+				"flags":         entries,
+			})
+		}
+
+		var b strings.Builder
+		b.WriteString(i18n.Sprintf("配置文件：%s（%s）", path, i18n.T(source)) + "\n\n")
+		w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, i18n.T("命令\t参数\t值\t来源"))
+		for _, e := range entries {
+			fmt.Fprintf(w, "%s\t--%s\t%s\t%s\n", e.Command, e.Flag, e.Value, e.Source)
+		}
+		_ = w.Flush()
+		return done(cmd, strings.TrimSuffix(b.String(), "\n"), nil)
+	},
+}
+
+func configKey(name string) string {
+	if key, ok := flagKeys[name]; ok {
+		return key
+	}
+	return name
+}
+
+func envName(key string) string {
+	return "SYSPROXY_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
+
+func commandName(c *cobra.Command) string {
+	if !c.HasParent() {
+		return "(全局)"
+	}
+	return strings.TrimPrefix(c.CommandPath(), c.Root().Name()+" ")
+}
+
+func resolveConfigPath() (string, string) {
+	if configPath != "" {
+		return configPath, "参数"
+	}
+	if path := os.Getenv("SYSPROXY_CONFIG"); path != "" {
+		return path, "环境变量 SYSPROXY_CONFIG"
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", "默认"
+	}
+	return filepath.Join(dir, "sysproxy", "config.json"), "默认"
+}
+
+func loadConfig() (*configFile, error) {
+	if loadedConfig != nil {
+		return loadedConfig, nil
+	}
+
+	loadedConfig = &configFile{}
+	path, source := resolveConfigPath()
+	if path == "" {
+		return loadedConfig, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// 仅默认路径允许不存在
+		if errors.Is(err, os.ErrNotExist) && source == "默认" {
+			return loadedConfig, nil
+		}
+		return loadedConfig, i18n.Errorf("读取配置文件失败：%w", err)
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return loadedConfig, i18n.Errorf("解析配置文件 %s 失败：%w", path, err)
+	}
+	loadedConfig.values = raw
+	if commands, ok := raw["commands"].(map[string]any); ok {
+		loadedConfig.commands = map[string]map[string]any{}
+		for name, values := range commands {
+			if v, ok := values.(map[string]any); ok {
+				loadedConfig.commands[name] = v
+			}
+		}
+	}
+	return loadedConfig, nil
+}
+
+// resolveFlag 返回参数的生效值与来源，仅在值来自环境变量或配置文件时 fromConfig 为 true
+func resolveFlag(c *cobra.Command, f *pflag.Flag) (value, source string, fromConfig bool) {
+	if f.Changed {
+		return f.Value.String(), i18n.T("参数"), false
+	}
+
+	key := configKey(f.Name)
+	if v, ok := os.LookupEnv(envName(key)); ok {
+		return v, i18n.Sprintf("环境变量 %s", envName(key)), true
+	}
+
+	file, _ := loadConfig()
+	if v, ok := file.commands[commandName(c)][key]; ok {
+		return formatConfigValue(v), i18n.Sprintf("配置文件 commands.%s.%s", commandName(c), key), true
+	}
+	if v, ok := file.values[key]; ok && key != "commands" {
+		return formatConfigValue(v), i18n.Sprintf("配置文件 %s", key), true
+	}
+	return f.DefValue, i18n.T("默认"), false
+}
+
+func formatConfigValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// applyConfig 使用环境变量与配置文件填充未在命令行中指定的参数
+func applyConfig(c *cobra.Command) error {
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+
+	var errs []error
+	c.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Name == "help" || f.Name == "config" {
+			return
+		}
+		value, source, fromConfig := resolveFlag(c, f)
+		if !fromConfig {
+			return
+		}
+		if err := f.Value.Set(value); err != nil {
+			errs = append(errs, i18n.Errorf("%s 的值 %q 无效：%w", source, value, err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+func visitCommands(c *cobra.Command, fn func(*cobra.Command, *pflag.Flag)) {
+	flags := c.LocalNonPersistentFlags()
+	if !c.HasParent() {
+		flags = c.PersistentFlags()
+	}
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Name != "help" && f.Name != "config" {
+			fn(c, f)
+		}
+	})
+	for _, sub := range c.Commands() {
+		if sub.Name() != "completion" && sub.Name() != "help" {
+			visitCommands(sub, fn)
+		}
+	}
+}
+
+func init() {
+	cmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd)
+
+	cmd.PersistentFlags().StringVar(&configPath, "config", "", "配置文件路径，默认为用户配置目录下的 sysproxy/config.json")
+	cmd.PersistentPreRunE = func(c *cobra.Command, args []string) error {
+		parsed = true
+		if err := setLang(); err != nil {
+			return err
+		}
+		if err := applyConfig(c); err != nil {
+			return &cliError{action: "参数无效", err: err, code: ExitInvalidInput}
+		}
+		// 语言也可能来自环境变量或配置文件
+		if err := setLang(); err != nil {
+			return err
+		}
+		if output != "text" && output != "json" {
+			return invalid("未知的输出格式：%s", output)
+		}
+		if err := setupLogger(); err != nil {
+			return err
+		}
+		if err := setupTrace(); err != nil {
+			return err
+		}
+		if err := setupAudit(); err != nil {
+			return err
+		}
+		startTime = time.Now()
+		return nil
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/xishang0128/sysproxy-go/sysproxy"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	shell       string
+	unset       bool
+	pacFallback string
+)
+
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "根据当前系统代理输出环境变量设置语句",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, ok := shellFormats[shell]
+		if !ok {
+			return invalid("不支持的 shell：%s", shell)
+		}
+
+		var lines []string
+		if unset {
+			for _, name := range sysproxy.ProxyEnvNames {
+				for _, n := range format.names(name) {
+					lines = append(lines, format.unset(n))
+				}
+			}
+			return done(cmd, strings.Join(lines, "\n"), map[string]any{"unset": sysproxy.ProxyEnvNames})
+		}
+
+		config, err := sysproxy.QueryProxySettings(device, onlyActiveDevice)
+		if err != nil {
+			return fail("查询代理设置失败", err)
+		}
+
+		switch config.Mode() {
+		case sysproxy.ModeProxy:
+		case sysproxy.ModePAC:
+			if pacFallback != "" {
+				bypass := config.Proxy.Bypass
+				config = sysproxy.NewProxyConfig(pacFallback, bypass)
+			} else {
+				config = nil
+			}
+		default:
+			config = nil
+		}
+
+		env := []sysproxy.EnvVar{}
+		if config != nil {
+			for _, v := range sysproxy.ProxyEnv(config) {
+				if v.Name != strings.ToLower(v.Name) && !format.caseSensitive {
+					continue
+				}
+				env = append(env, v)
+				lines = append(lines, format.export(v.Name, v.Value))
+			}
+		}
+		return done(cmd, strings.Join(lines, "\n"), map[string]any{"env": env})
+	},
+}
+
+type shellFormat struct {
+	caseSensitive bool
+	export        func(name, value string) string
+	unset         func(name string) string
+}
+
+func (f shellFormat) names(name string) []string {
+	if f.caseSensitive {
+		return []string{name, strings.ToUpper(name)}
+	}
+	return []string{name}
+}
+
+var shellFormats = map[string]shellFormat{
+	"bash": {
+		caseSensitive: true,
+		export: func(name, value string) string {
+			return fmt.Sprintf("export %s='%s'", name, strings.ReplaceAll(value, "'", `'\''`))
+		},
+		unset: func(name string) string {
+			return "unset " + name
+		},
+	},
+	"fish": {
+		caseSensitive: true,
+		export: func(name, value string) string {
+			value = strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+			return fmt.Sprintf("set -gx %s '%s'", name, value)
+		},
+		unset: func(name string) string {
+			return "set -e " + name
+		},
+	},
+	"powershell": {
+		export: func(name, value string) string {
+			return fmt.Sprintf("$env:%s = '%s'", name, strings.ReplaceAll(value, "'", "''"))
+		},
+		unset: func(name string) string {
+			return fmt.Sprintf("Remove-Item Env:%s -ErrorAction SilentlyContinue", name)
+		},
+	},
+	"cmd": {
+		export: func(name, value string) string {
+			return fmt.Sprintf(`set "%s=%s"`, name, value)
+		},
+		unset: func(name string) string {
+			return fmt.Sprintf(`set "%s="`, name)
+		},
+	},
+}
+
+func defaultShell() string {
+	if runtime.GOOS == "windows" {
+		return "powershell"
+	}
+	return "bash"
+}
+
+func init() {
+	shellFormats["sh"] = shellFormats["bash"]
+	shellFormats["zsh"] = shellFormats["bash"]
+
+	cmd.AddCommand(envCmd)
+
+	envCmd.Flags().StringVar(&shell, "shell", defaultShell(), "输出格式：bash、zsh、sh、fish、powershell、cmd")
+	envCmd.Flags().BoolVar(&unset, "unset", false, "输出取消代理环境变量的语句")
+	envCmd.Flags().StringVar(&pacFallback, "pac-fallback", "", "PAC 模式下使用的代理地址，默认不输出")
+}
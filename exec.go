@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/xishang0128/sysproxy-go/i18n"
+	"github.com/xishang0128/sysproxy-go/sysproxy"
+
+	"github.com/spf13/cobra"
+)
+
+var inherit bool
+
+var execCmd = &cobra.Command{
+	Use:   "exec [--profile name | --server addr | --inherit] -- command [args...]",
+	Short: "仅为指定命令设置代理环境变量，不修改系统设置",
+	Args:  cobra.MinimumNArgs(1),
+	// 标准输出属于子进程，结果输出到标准错误
+	Annotations: map[string]string{"output": "stderr"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config := &sysproxy.ProxyConfig{}
+		if profile != "" {
+			p, err := loadProfile(profile)
+			if err != nil {
+				return fail("读取配置失败", err)
+			}
+			if p.Mode != sysproxy.ModeProxy {
+				return invalid("配置 %s 不是 proxy 模式", profile)
+			}
+			config = p.Config()
+		} else if inherit {
+			current, err := sysproxy.QueryProxySettings(device, onlyActiveDevice)
+			if err != nil {
+				return fail("查询代理设置失败", err)
+			}
+			if current.Mode() == sysproxy.ModeProxy {
+				config = current
+			} else {
+				fmt.Fprintln(os.Stderr, i18n.Sprintf("当前系统未使用手动代理，模式：%s", current.Mode()))
+			}
+		}
+		if server != "" {
+			config.Proxy.Servers = map[string]string{
+				"http_server":  server,
+				"https_server": server,
+			}
+		}
+		if bypass != "" {
+			config.Proxy.Bypass = bypass
+		}
+
+		env := sysproxy.ProxyEnv(config)
+		if len(env) == 0 && !inherit {
+			return invalid("需要指定 --server、--profile 或 --inherit")
+		}
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+		defer signal.Stop(signals)
+
+		if code := runChildEnv(args, proxyEnviron(env), signals); code != 0 {
+			return &childExit{code: code}
+		}
+		return done(cmd, "", map[string]int{"exit_code": 0})
+	},
+}
+
+// proxyEnviron 在当前环境的基础上替换所有代理相关的环境变量
+func proxyEnviron(env []sysproxy.EnvVar) []string {
+	var environ []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if !slices.Contains(sysproxy.ProxyEnvNames, strings.ToLower(name)) {
+			environ = append(environ, kv)
+		}
+	}
+	for _, v := range env {
+		environ = append(environ, v.Name+"="+v.Value)
+	}
+	return environ
+}
+
+func init() {
+	cmd.AddCommand(execCmd)
+
+	execCmd.Flags().SetInterspersed(false)
+	execCmd.Flags().StringVarP(&server, "server", "s", "", "代理服务器地址")
+	execCmd.Flags().StringVarP(&bypass, "bypass", "b", "", "绕过地址，转换为 NO_PROXY")
+	execCmd.Flags().BoolVar(&inherit, "inherit", false, "使用当前系统代理设置")
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/xishang0128/sysproxy-go/i18n"
+	"github.com/xishang0128/sysproxy-go/sysproxy"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	guardMinInterval time.Duration
+	guardMaxBackoff  time.Duration
+)
+
+var guardCmd = &cobra.Command{
+	Use:   "guard",
+	Short: "保持代理设置，被修改后自动恢复",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if server == "" && pacUrl == "" {
+			return invalid("需要指定 --server 或 --url")
+		}
+
+		desired := sysproxy.NewProxyConfig(server, bypass)
+		if pacUrl != "" {
+			desired = sysproxy.NewPacConfig(pacUrl)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		guard := &sysproxy.Guard{
+			Desired:          desired,
+			Device:           device,
+			OnlyActiveDevice: onlyActiveDevice,
+			MinInterval:      guardMinInterval,
+			MaxBackoff:       guardMaxBackoff,
+			Backend:          auditedBackend{apply: "guard_correct", restore: "guard_correct"},
+			OnCorrect: func(c sysproxy.Correction) {
+				if c.Error != "" {
+					emit(i18n.Sprintf("恢复代理设置失败：%s", c.Error), c)
+					return
+				}
+				emit(i18n.Sprintf("代理设置被修改，已恢复：%s", strings.Join(c.Diff, "; ")), c)
+			},
+		}
+		err := audit("guard", func() error {
+			return sysproxy.Apply(desired, device, onlyActiveDevice)
+		})
+		if err != nil {
+			return fail("设置代理失败", err)
+		}
+		guard.Hold(ctx)
+		return done(cmd, "", guard.Status())
+	},
+}
+
+func init() {
+	cmd.AddCommand(guardCmd)
+
+	guardCmd.Flags().StringVarP(&server, "server", "s", "", "代理服务器地址")
+	guardCmd.Flags().StringVarP(&bypass, "bypass", "b", "", "绕过地址")
+	guardCmd.Flags().StringVarP(&pacUrl, "url", "u", "", "pac 地址")
+	guardCmd.Flags().DurationVar(&guardMinInterval, "min-interval", 5*time.Second, "两次恢复之间的最小间隔")
+	guardCmd.Flags().DurationVar(&guardMaxBackoff, "max-backoff", 5*time.Minute, "连续恢复时的最大退避时间")
+}
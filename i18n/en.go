@@ -0,0 +1,294 @@
+package i18n
+
+// en 按命令与功能分组，新增的翻译放在对应的分组中，避免对齐整个表
+var en = map[string]string{
+	// cli
+	"系统代理设置工具":              "system proxy configuration tool",
+	"参数无效":                  "invalid argument",
+	"%s：%v":                 "%s: %v",
+	"使用 \"%s --help\" 查看用法": "Run \"%s --help\" for usage",
+	"执行失败":                  "failed",
+	"命令退出码：%d":              "command exited with code %d",
+
+	// output
+	"输出格式：text、json":  "output format: text, json",
+	"未知的输出格式：%s":      "unknown output format: %s",
+	"设置后重新查询以验证是否生效":  "query again after setting to verify it took effect",
+	"设置后的代理与期望不一致：%s": "applied proxy differs from the requested settings: %s",
+	"验证代理设置失败":        "failed to verify proxy settings",
+	"格式化 JSON 失败":     "failed to format JSON",
+
+	// lang
+	"界面语言：zh-CN、en，默认根据 LANG 等环境变量选择": "interface language: zh-CN, en, defaults to LANG and related environment variables",
+	"不支持的语言：%s，可选 zh-CN、en":           "unsupported language: %s, expected zh-CN or en",
+
+	// config
+	"管理默认配置":        "manage default settings",
+	"查看各参数的生效值及其来源": "show the effective value and source of each flag",
+	"配置文件路径，默认为用户配置目录下的 sysproxy/config.json": "config file path, defaults to sysproxy/config.json in the user config directory",
+	"配置文件：%s（%s）":          "Config file: %s (%s)",
+	"命令\t参数\t值\t来源":        "COMMAND\tFLAG\tVALUE\tSOURCE",
+	"(全局)":                 "(global)",
+	"参数":                   "flag",
+	"默认":                   "default",
+	"环境变量 %s":              "environment variable %s",
+	"环境变量 SYSPROXY_CONFIG": "environment variable SYSPROXY_CONFIG",
+	"配置文件 %s":              "config file %s",
+	"配置文件 commands.%s.%s":  "config file commands.%s.%s",
+	"%s 的值 %q 无效：%w":       "%s: invalid value %q: %w",
+	"读取配置文件失败：%w":          "failed to read config file: %w",
+	"解析配置文件 %s 失败：%w":      "failed to parse config file %s: %w",
+
+	// log
+	"日志级别：debug、info、warn、error": "log level: debug, info, warn, error",
+	"日志格式：text、json":             "log format: text, json",
+	"日志文件路径，默认输出到标准错误":           "log file path, defaults to standard error",
+	"未知的日志级别：%s":                 "unknown log level: %s",
+	"未知的日志格式：%s":                 "unknown log format: %s",
+	"打开日志文件失败":                   "failed to open log file",
+
+	// trace
+	"跟踪外部命令与系统接口调用：timeline、json，仅指定 --trace 时为 timeline": "trace external commands and system API calls: timeline, json; --trace alone means timeline",
+	"将跟踪写入文件，默认输出到标准错误":                                   "write the trace to a file, defaults to standard error",
+	"未知的跟踪格式：%s":                                          "unknown trace format: %s",
+	"打开跟踪文件失败":                                            "failed to open trace file",
+
+	// audit
+	"查看代理设置的审计日志":             "view the audit log of proxy setting changes",
+	"输出最近的审计记录，以 JSON 行输出":    "print recent audit records as JSON lines",
+	"按时间与操作查询审计记录，以 JSON 行输出": "query audit records by time and operation as JSON lines",
+	"读取审计日志失败":                "failed to read audit log",
+	"输出审计记录失败":                "failed to write audit record",
+	"审计日志的大小与保留数量不能为负数":       "audit log size and backup count must not be negative",
+	"无效的时间：%s":                "invalid time: %s",
+	"将修改代理设置的操作记录到审计日志，仅指定 --audit-log 时使用默认路径": "record proxy setting changes to an audit log; the default path is used when --audit-log is given without a value",
+	"审计日志轮转的大小（MiB）":                                 "size in MiB at which the audit log is rotated",
+	"保留的已轮转审计日志数量":                                   "number of rotated audit logs to keep",
+	"起始时间，如 2006-01-02 15:04、RFC 3339 时间或 1h（1 小时前）": "start time, e.g. 2006-01-02 15:04, an RFC 3339 time or 1h (one hour ago)",
+	"结束时间，格式同 --since":                               "end time, same format as --since",
+	"只输出指定的操作，如 proxy、pac、disable、guard_start，可重复指定": "only print the given operations, e.g. proxy, pac, disable, guard_start; repeatable",
+	"输出的记录数":   "number of records to print",
+	"持续输出新的记录": "keep printing new records",
+
+	// proxy、pac、disable、status
+	"设置系统代理":           "set the system proxy",
+	"设置 PAC 代理":        "set a PAC proxy",
+	"取消代理设置":           "disable the system proxy",
+	"查看当前代理设置":         "show the current proxy settings",
+	"代理服务器地址":          "proxy server address",
+	"绕过地址":             "bypass list",
+	"pac 地址":           "PAC URL",
+	"指定网络设备":           "network device to use",
+	"仅对活跃的网络设备生效":      "apply to active network devices only",
+	"代理设置成功，耗时：%v":     "proxy set, took %v",
+	"PAC 代理设置成功，耗时：%v": "PAC proxy set, took %v",
+	"代理设置已取消，耗时：%v":    "proxy disabled, took %v",
+	"设置代理失败":           "failed to set proxy",
+	"设置 PAC 代理失败":      "failed to set PAC proxy",
+	"取消代理设置失败":         "failed to disable proxy",
+	"查询代理设置失败":         "failed to query proxy settings",
+
+	// run
+	"设置代理后运行命令，命令退出后恢复原设置":  "set the proxy, run a command and restore the previous settings when it exits",
+	"需要指定 --server 或 --url": "--server or --url is required",
+	"启动命令失败：%v":             "failed to start command: %v",
+	"恢复代理设置失败":              "failed to restore proxy settings",
+
+	// env、exec
+	"根据当前系统代理输出环境变量设置语句":                   "print shell statements exporting the current system proxy",
+	"仅为指定命令设置代理环境变量，不修改系统设置":               "set proxy environment variables for a single command without touching system settings",
+	"输出格式：bash、zsh、sh、fish、powershell、cmd": "output syntax: bash, zsh, sh, fish, powershell, cmd",
+	"输出取消代理环境变量的语句":                        "print statements unsetting the proxy variables",
+	"绕过地址，转换为 NO_PROXY":                    "bypass list, converted to NO_PROXY",
+	"使用当前系统代理设置":                           "use the current system proxy settings",
+	"使用指定代理配置":                             "use the named profile",
+	"PAC 模式下使用的代理地址，默认不输出":                 "proxy address to use in PAC mode, nothing is printed by default",
+	"当前系统未使用手动代理，模式：%s":                    "system is not using a manual proxy, mode: %s",
+	"配置 %s 不是 proxy 模式":                    "profile %s is not in proxy mode",
+	"不支持的 shell：%s":                        "unsupported shell: %s",
+
+	// profiles
+	"管理代理配置":    "manage profiles",
+	"列出所有代理配置":  "list all profiles",
+	"查看代理配置":    "show a profile",
+	"应用指定的代理配置": "apply a named profile",
+	"代理配置文件路径，默认为用户配置目录下的 sysproxy/profiles.json": "profiles file path, defaults to sysproxy/profiles.json in the user config directory",
+	"已应用配置 %s，耗时：%v":                "applied profile %s, took %v",
+	"应用配置失败":                        "failed to apply profile",
+	"读取配置失败":                        "failed to read profiles",
+	"读取配置失败：%v":                     "failed to read profiles: %v",
+	"未找到配置：%s":                      "profile not found: %s",
+	"配置 %s 无效：%v":                   "profile %s is invalid: %v",
+	"解析配置文件 %s 失败：%v":               "failed to parse config file %s: %v",
+	"proxy 模式需要指定 server 或 servers": "proxy mode requires server or servers",
+	"pac 模式需要指定 pac_url":            "pac mode requires pac_url",
+	"未知的模式：%s":                      "unknown mode: %s",
+
+	// auto
+	"网络变化时按规则自动切换代理配置": "switch profiles automatically by rules when the network changes",
+	"查看当前网络环境及匹配的配置":   "show the detected network and the matching profile",
+	"网络已变化，切换到配置：%s":   "network changed, switched to profile: %s",
+	"切换到配置 %s 失败：%v":   "failed to switch to profile %s: %v",
+	"自动切换失败":           "automatic switching failed",
+	"查询网络环境失败":         "failed to query network",
+	"配置文件中没有规则":        "no rules in the profiles file",
+	"第 %d 条规则无效：%v":    "rule %d is invalid: %v",
+	"规则需要指定 profile":   "rule requires a profile",
+	"无效的网关地址：%s":       "invalid gateway address: %s",
+	"无效的子网：%s":         "invalid subnet: %s",
+	"无法获取网络接口：%v":      "failed to list network interfaces: %v",
+	"无法订阅网络变化时的轮询间隔，以及应用失败后重试的间隔": "polling interval when network changes cannot be subscribed to, and retry interval after a failed switch",
+
+	// watch
+	"监听代理设置变化，以 JSON 行输出": "watch proxy settings and print changes as JSON lines",
+	"无变更通知时的轮询间隔":         "polling interval when change notifications are unavailable",
+	"输出事件失败":              "failed to write event",
+
+	// guard
+	"保持代理设置，被修改后自动恢复":                     "keep proxy settings and restore them when changed",
+	"代理设置被修改，已恢复：%s":                      "proxy settings were changed and have been restored: %s",
+	"恢复代理设置失败：%s":                         "failed to restore proxy settings: %s",
+	"两次恢复之间的最小间隔":                         "minimum interval between two restores",
+	"连续恢复时的最大退避时间":                        "maximum backoff between consecutive restores",
+	"需要指定 --server、--profile 或 --inherit": "--server, --profile or --inherit is required",
+	"当前未设置代理，请使用 --server 指定":             "no proxy is set, specify one with --server",
+
+	// watchdog
+	"代理端点失效时切换到直连，恢复后重新设置代理": "switch to direct when the proxy endpoint fails and restore the proxy once it recovers",
+	"代理服务器地址，为空时使用当前设置":      "proxy server address, defaults to the current setting",
+	"失效时使用的备用代理地址，默认直连":      "fallback proxy address used on failure, defaults to direct",
+	"失效时使用的备用 pac 地址":        "fallback PAC URL used on failure",
+	"探测方式：tcp、http、socks5":   "probe type: tcp, http, socks5",
+	"探测间隔":                   "probe interval",
+	"单次探测超时":                 "timeout of a single probe",
+	"连续失败多少次后切换":             "consecutive failures before switching",
+	"代理端点已恢复：%s":             "proxy endpoint recovered: %s",
+	"代理端点探测失败：%s":            "proxy endpoint probe failed: %s",
+	"代理端点无响应，已切换到备用设置：%s":    "proxy endpoint not responding, switched to fallback: %s",
+	"启动看门狗失败":                "failed to start watchdog",
+	"未知的探测方式：%s":             "unknown probe type: %s",
+	"无效的代理地址：%s":             "invalid proxy address: %s",
+	"无效的时间间隔：%s":             "invalid duration: %s",
+	"无效的 HTTP 响应：%q":         "invalid HTTP response: %q",
+	"无效的 SOCKS5 响应：%x":       "invalid SOCKS5 response: %x",
+	"查询失败：%v":                "query failed: %v",
+
+	// server
+	"启动监听服务": "start the listening service",
+	"监听地址，Linux 下以 @ 开头时为抽象命名空间套接字": "listen address, a leading @ means an abstract socket on Linux",
+	"无请求超过该时间后退出，0 表示不退出":           "exit after no requests for this long, 0 disables",
+	"停止时等待进行中请求完成的最长时间":             "maximum time to wait for in-flight requests when stopping",
+	"退出时恢复启动时的代理设置":                 "restore the proxy settings from startup on exit",
+	"提供 Prometheus 格式的 /metrics 接口": "serve Prometheus metrics at /metrics",
+	"代理服务已启动，监听：%s":                 "Proxy service started, listening on: %s",
+	"代理服务已停止":                       "proxy service stopped",
+	"启动代理服务失败":                      "failed to start proxy service",
+	"停止代理服务失败":                      "failed to stop proxy service",
+	"等待请求完成超时：%v":                   "timed out waiting for requests to finish: %v",
+	"创建目录失败：%v":                     "failed to create directory: %v",
+	"删除旧的监听文件失败：%v":                 "failed to remove stale socket: %v",
+	"监听 unix 套接字失败：%v":              "failed to listen on unix socket: %v",
+	"无法使用 systemd 传入的套接字：%v":        "cannot use socket passed by systemd: %v",
+	"无法获取 launchd 传入的套接字：%v":        "cannot get socket passed by launchd: %v",
+
+	// server：权限
+	"无效的套接字权限：%s":       "invalid socket mode: %s",
+	"套接字文件权限（八进制）":      "socket file mode (octal)",
+	"套接字文件属主，用户名或 uid":  "socket file owner, user name or uid",
+	"套接字文件属组，组名或 gid":   "socket file group, group name or gid",
+	"允许修改设置的 uid，可重复指定": "uid allowed to change settings, repeatable",
+	"允许修改设置的 gid，可重复指定": "gid allowed to change settings, repeatable",
+	"允许修改设置的程序路径，可重复指定": "executable path allowed to change settings, repeatable",
+	"无权执行此操作":           "permission denied",
+	"设置套接字权限失败：%v":      "failed to set socket mode: %v",
+	"设置套接字属主失败：%v":      "failed to set socket owner: %v",
+	"未知的用户：%s":          "unknown user: %s",
+	"未知的用户组：%s":         "unknown group: %s",
+
+	// server：TCP
+	"同时监听的 TCP 地址，如 127.0.0.1:9090，请求需携带令牌": "also listen on a TCP address such as 127.0.0.1:9090, requests need the token",
+	"令牌文件路径":          "token file path",
+	"允许 TCP 监听非回环地址":  "allow the TCP listener on non-loopback addresses",
+	"无效的监听地址：%s":      "invalid listen address: %s",
+	"拒绝在非回环地址 %s 上监听": "refusing to listen on non-loopback address %s",
+	"监听 TCP 地址失败：%v":  "failed to listen on TCP address: %v",
+	"生成令牌失败：%v":       "failed to generate token: %v",
+	"写入令牌文件失败：%v":     "failed to write token file: %v",
+	"令牌无效":            "invalid token",
+
+	// 接口
+	"系统代理设置服务，TCP 监听时需要携带令牌": "system proxy settings service, requests over TCP need the token",
+	"获取 OpenAPI 文档": "get the OpenAPI document",
+	"检查服务是否可用并获取版本": "check that the service is available and get its version",
+	"查询代理设置":        "query proxy settings",
+	"查看保持状态":        "show guard status",
+	"应用并保持代理设置":     "apply and guard proxy settings",
+	"停止保持":          "stop the guard",
+	"查看看门狗状态":       "show watchdog status",
+	"设置代理并启动看门狗":    "set the proxy and start the watchdog",
+	"停止看门狗":         "stop the watchdog",
+	"订阅代理设置变化":      "subscribe to proxy setting changes",
+	"Server-Sent Events 流，连接后先发送 snapshot 事件，之后每次变化发送 change 事件，data 均为 StateEvent": "Server-Sent Events stream: a snapshot event on connect, then a change event for every change; data is a StateEvent",
+	"连接不支持流式响应":                             "the connection does not support streaming responses",
+	"接口不存在：%s":                              "no such endpoint: %s",
+	"不支持的请求方法：%s":                           "method not allowed: %s",
+	"解析请求失败：%v":                             "failed to parse request: %v",
+	"参数无效：%s":                               "invalid parameters: %s",
+	"无效的值：%s":                               "invalid value: %s",
+	"未知的参数":                                 "unknown parameter",
+	"需要指定 server 或 servers":                 "server or servers is required",
+	"需要指定 url":                              "url is required",
+	"需要指定 server 或 url":                     "server or url is required",
+	"无效的 URL：%s":                            "invalid URL: %s",
+	"server 与 url 只能指定一个":                   "only one of server and url may be given",
+	"失败次数不能为负数":                             "failures must not be negative",
+	"fallback_server 与 fallback_url 只能指定一个": "only one of fallback_server and fallback_url may be given",
+	"当前未设置代理，需要指定 server":                   "no proxy is set, server is required",
+	"保持或看门狗正在运行":                            "a guard or watchdog is running",
+	"保持或看门狗正在运行，指定 force 以停止它们":             "a guard or watchdog is running, set force to stop it",
+	"未知的协议：%s":                              "unknown protocol: %s",
+
+	// 租约
+	"查看租约":            "list leases",
+	"创建或续期租约":         "acquire or renew a lease",
+	"续期租约":            "renew a lease",
+	"释放租约":            "release a lease",
+	"需要指定 owner":      "owner is required",
+	"需要指定 ttl 或 hold": "ttl or hold is required",
+	"未知的过期操作：%s":      "unknown expiry action: %s",
+	"未找到租约：%s":        "lease not found: %s",
+
+	// client
+	"服务的接口版本为 %d（%s），客户端需要 %d": "the service API version is %d (%s), the client requires %d",
+	"解析响应失败：%w":                "failed to parse response: %w",
+	"服务返回 %s：%s":               "service returned %s: %s",
+	"租约连接已断开":                  "lease connection closed",
+
+	// install
+	"生成按套接字启动的 systemd 用户单元": "generate socket-activated systemd user units",
+	"获取程序路径失败":               "failed to locate executable",
+	"获取用户配置目录失败":             "failed to locate user config directory",
+	"创建目录失败":                 "failed to create directory",
+	"写入单元文件失败":               "failed to write unit file",
+	"已写入 %s\n启用：systemctl --user daemon-reload && systemctl --user enable --now %s.socket": "wrote %s\nenable with: systemctl --user daemon-reload && systemctl --user enable --now %s.socket",
+	"套接字地址，%t 为 $XDG_RUNTIME_DIR，以 @ 开头时为抽象命名空间套接字":                                        "socket address, %t is $XDG_RUNTIME_DIR, a leading @ means an abstract socket",
+	"服务无请求超过该时间后退出，0 表示不退出":                                                                "service exits after no requests for this long, 0 disables",
+	"单元文件目录，默认为 ~/.config/systemd/user":                                                    "unit directory, defaults to ~/.config/systemd/user",
+	"单元名称": "unit name",
+	"仅输出单元内容，不写入文件": "print the units instead of writing files",
+
+	// 平台
+	"不支持的操作系统":                        "unsupported operating system",
+	"未支持%s":                           "unsupported on %s",
+	"不支持的桌面：%s":                       "unsupported desktop: %s",
+	"未设置 XDG_CURRENT_DESKTOP 环境变量":    "XDG_CURRENT_DESKTOP environment variable not set",
+	"无法读取 %s 的 GNOME 配置：%v":           "failed to read GNOME setting %s: %v",
+	"无法读取 %s 的 KDE 配置：%v":             "failed to read KDE setting %s: %v",
+	"获取连接名失败：%v":                      "failed to list connection names: %v",
+	"设置 %s 连接失败：%v":                   "failed to configure connection %s: %v",
+	"networksetup 命令没有输出":             "networksetup produced no output",
+	"无法执行 networksetup 命令：%v":         "failed to run networksetup: %v",
+	"执行 networksetup %v 时出错，服务 %s：%v": "networksetup %v failed for service %s: %v",
+	"未找到活跃的网络服务":                      "no active network service found",
+	"扫描输出时出错：%v":                      "failed to scan output: %v",
+}
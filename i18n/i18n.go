@@ -0,0 +1,95 @@
+// Package i18n 提供命令行与 sysproxy 包的消息翻译。
+//
+// 源码中的消息即为 zh-CN 文本，同时作为查找其他语言翻译的键。
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	ZhCN = "zh-CN"
+	En   = "en"
+)
+
+var catalogs = map[string]map[string]string{
+	En: en,
+}
+
+var (
+	mu      sync.RWMutex
+	current string
+	once    sync.Once
+)
+
+// Detect 依次读取 LC_ALL、LC_MESSAGES、LANG，均未设置时使用系统首选语言，
+// 无法识别的语言使用 en
+func Detect() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(name); v != "" {
+			return parseOr(v, En)
+		}
+	}
+	return parseOr(systemLang(), ZhCN)
+}
+
+// Parse 将 zh_CN.UTF-8、en-US 等语言标识转换为支持的语言
+func Parse(lang string) (string, bool) {
+	lang, _, _ = strings.Cut(lang, ".")
+	lang, _, _ = strings.Cut(lang, "@")
+	primary, _, _ := strings.Cut(strings.ReplaceAll(lang, "_", "-"), "-")
+	switch strings.ToLower(primary) {
+	case "zh":
+		return ZhCN, true
+	case "en":
+		return En, true
+	}
+	return "", false
+}
+
+func parseOr(lang, fallback string) string {
+	if lang == "" {
+		return fallback
+	}
+	if l, ok := Parse(lang); ok {
+		return l
+	}
+	return En
+}
+
+func SetLang(lang string) {
+	once.Do(func() {})
+	mu.Lock()
+	current = lang
+	mu.Unlock()
+}
+
+func Lang() string {
+	once.Do(func() {
+		mu.Lock()
+		current = Detect()
+		mu.Unlock()
+	})
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// T 返回 msg 在当前语言下的翻译，没有翻译时原样返回
+func T(msg string) string {
+	if s, ok := catalogs[Lang()][msg]; ok {
+		return s
+	}
+	return msg
+}
+
+func Sprintf(format string, args ...any) string {
+	return fmt.Sprintf(T(format), args...)
+}
+
+func Errorf(format string, args ...any) error {
+	return fmt.Errorf(T(format), args...)
+}
@@ -0,0 +1,7 @@
+//go:build !windows
+
+package i18n
+
+func systemLang() string {
+	return ""
+}
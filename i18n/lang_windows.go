@@ -0,0 +1,11 @@
+package i18n
+
+import "golang.org/x/sys/windows"
+
+func systemLang() string {
+	langs, err := windows.GetUserPreferredUILanguages(windows.MUI_LANGUAGE_NAME)
+	if err != nil || len(langs) == 0 {
+		return ""
+	}
+	return langs[0]
+}
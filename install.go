@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xishang0128/sysproxy-go/i18n"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	installListen      string
+	installIdleTimeout time.Duration
+	installDir         string
+	installName        string
+	installPrint       bool
+)
+
+var serverInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "生成按套接字启动的 systemd 用户单元",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		exe, err := os.Executable()
+		if err != nil {
+			return fail("获取程序路径失败", err)
+		}
+		units := map[string]string{
+			installName + ".socket":  socketUnit(installListen),
+			installName + ".service": serviceUnit(exe, installIdleTimeout),
+		}
+		names := []string{installName + ".socket", installName + ".service"}
+
+		if installPrint {
+			var b strings.Builder
+			for _, name := range names {
+				fmt.Fprintf(&b, "# %s\n%s\n", name, units[name])
+			}
+			return done(cmd, strings.TrimSuffix(b.String(), "\n"), units)
+		}
+
+		dir := installDir
+		if dir == "" {
+			config, err := os.UserConfigDir()
+			if err != nil {
+				return fail("获取用户配置目录失败", err)
+			}
+			dir = filepath.Join(config, "systemd", "user")
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fail("创建目录失败", err)
+		}
+		var paths []string
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, []byte(units[name]), 0o644); err != nil {
+				return fail("写入单元文件失败", err)
+			}
+			paths = append(paths, path)
+		}
+		text := i18n.Sprintf("已写入 %s\n启用：systemctl --user daemon-reload && systemctl --user enable --now %s.socket",
+			strings.Join(paths, "、"), installName)
+		return done(cmd, text, map[string]any{"files": paths})
+	},
+}
+
+func socketUnit(listen string) string {
+	return fmt.Sprintf(`[Unit]
+Description=sysproxy helper socket
+
+[Socket]
+ListenStream=%s
+SocketMode=0600
+
+[Install]
+WantedBy=sockets.target
+`, listen)
+}
+
+func serviceUnit(exe string, idleTimeout time.Duration) string {
+	execStart := systemdQuote(exe) + " server"
+	if idleTimeout > 0 {
+		execStart += " --idle-timeout " + idleTimeout.String()
+	}
+	return fmt.Sprintf(`[Unit]
+Description=sysproxy helper
+Requires=%s.socket
+After=%s.socket
+
+[Service]
+Type=notify
+ExecStart=%s
+`, installName, installName, execStart)
+}
+
+// systemdQuote 为含空格的路径加引号，并转义 systemd 的 % 说明符
+func systemdQuote(s string) string {
+	s = strings.ReplaceAll(s, "%", "%%")
+	if strings.ContainsAny(s, " \t\"\\") {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+	}
+	return s
+}
+
+func init() {
+	serverCmd.AddCommand(serverInstallCmd)
+
+	serverInstallCmd.Flags().StringVarP(&installListen, "listen", "l", "%t/sysproxy.sock", "套接字地址，%t 为 $XDG_RUNTIME_DIR，以 @ 开头时为抽象命名空间套接字")
+	serverInstallCmd.Flags().DurationVar(&installIdleTimeout, "idle-timeout", 5*time.Minute, "服务无请求超过该时间后退出，0 表示不退出")
+	serverInstallCmd.Flags().StringVar(&installDir, "dir", "", "单元文件目录，默认为 ~/.config/systemd/user")
+	serverInstallCmd.Flags().StringVar(&installName, "name", "sysproxy", "单元名称")
+	serverInstallCmd.Flags().BoolVar(&installPrint, "print", false, "仅输出单元内容，不写入文件")
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"github.com/xishang0128/sysproxy-go/i18n"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var lang string
+
+// setLang 使用 --lang 指定的语言，未指定时由 i18n 根据 LANG 等环境变量选择
+func setLang() error {
+	if lang == "" {
+		return nil
+	}
+	l, ok := i18n.Parse(lang)
+	if !ok {
+		return invalid("不支持的语言：%s，可选 zh-CN、en", lang)
+	}
+	i18n.SetLang(l)
+	return nil
+}
+
+// localize 翻译命令说明与参数说明，仅在输出帮助时使用
+func localize(c *cobra.Command) {
+	c.Short = i18n.T(c.Short)
+	c.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		f.Usage = i18n.T(f.Usage)
+	})
+	for _, sub := range c.Commands() {
+		localize(sub)
+	}
+}
+
+func init() {
+	cmd.PersistentFlags().StringVar(&lang, "lang", "", "界面语言：zh-CN、en，默认根据 LANG 等环境变量选择")
+
+	help := cmd.HelpFunc()
+	cmd.SetHelpFunc(func(c *cobra.Command, args []string) {
+		_ = setLang()
+		localize(c.Root())
+		help(c, args)
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/xishang0128/sysproxy-go/sysproxy"
+)
+
+var (
+	logLevel  string
+	logFormat string
+	logFile   string
+)
+
+// setupLogger 按参数创建日志记录器，日志默认输出到标准错误，不影响命令输出
+func setupLogger() error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(logLevel)); err != nil {
+		return invalid("未知的日志级别：%s", logLevel)
+	}
+
+	var w io.Writer = os.Stderr
+	if logFile != "" {
+		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return fail("打开日志文件失败", err)
+		}
+		w = f
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(logFormat) {
+	case "text":
+		handler = slog.NewTextHandler(w, options)
+	case "json":
+		handler = slog.NewJSONHandler(w, options)
+	default:
+		return invalid("未知的日志格式：%s", logFormat)
+	}
+
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+	sysproxy.SetLogger(logger)
+	return nil
+}
+
+func init() {
+	cmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "日志级别：debug、info、warn、error")
+	cmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "日志格式：text、json")
+	cmd.PersistentFlags().StringVar(&logFile, "log-file", "", "日志文件路径，默认输出到标准错误")
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/xishang0128/sysproxy-go/i18n"
 	"github.com/xishang0128/sysproxy-go/sysproxy"
 
 	"github.com/spf13/cobra"
@@ -16,87 +21,167 @@ var (
 	bypass string
 	pacUrl string
 
-	listen string
-	device string
+	listen      string
+	idleTimeout time.Duration
+	device      string
+
+	socketMode  string
+	socketOwner string
+	socketGroup string
+	allowUIDs   []int
+	allowGIDs   []int
+	allowExes   []string
+
+	tcpListen   string
+	tokenFile   string
+	allowRemote bool
+
+	shutdownTimeout time.Duration
+	restoreOnExit   bool
+	metrics         bool
 
 	onlyActiveDevice bool
 )
 
 var cmd = &cobra.Command{
-	Use:   "sysproxy",
-	Short: "系统代理设置工具",
+	Use:           "sysproxy",
+	Short:         "系统代理设置工具",
+	SilenceErrors: true,
+	SilenceUsage:  true,
 }
 
 var proxyCmd = &cobra.Command{
 	Use:   "proxy",
 	Short: "设置系统代理",
-	Run: func(cmd *cobra.Command, args []string) {
-		t := time.Now()
-		err := sysproxy.SetProxy(server, bypass, device, onlyActiveDevice)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := audited("proxy", func() error {
+			return sysproxy.SetProxy(server, bypass, device, onlyActiveDevice)
+		})
 		if err != nil {
-			fmt.Println("设置代理失败：", err)
-			return
+			return fail("设置代理失败", err)
 		}
-		fmt.Println("代理设置成功，耗时：", time.Since(t))
+		if err := verifyApplied(sysproxy.NewProxyConfig(server, bypass)); err != nil {
+			return err
+		}
+		return done(cmd, i18n.Sprintf("代理设置成功，耗时：%v", elapsed()), nil)
 	},
 }
 
 var pacCmd = &cobra.Command{
 	Use:   "pac",
 	Short: "设置 PAC 代理",
-	Run: func(cmd *cobra.Command, args []string) {
-		t := time.Now()
-		err := sysproxy.SetPac(pacUrl, device, onlyActiveDevice)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := audited("pac", func() error {
+			return sysproxy.SetPac(pacUrl, device, onlyActiveDevice)
+		})
 		if err != nil {
-			fmt.Println("设置 PAC 代理失败：", err)
-			return
+			return fail("设置 PAC 代理失败", err)
+		}
+		if err := verifyApplied(sysproxy.NewPacConfig(pacUrl)); err != nil {
+			return err
 		}
-		fmt.Println("PAC 代理设置成功，耗时：", time.Since(t))
+		return done(cmd, i18n.Sprintf("PAC 代理设置成功，耗时：%v", elapsed()), nil)
 	},
 }
 
 var disableCmd = &cobra.Command{
 	Use:   "disable",
 	Short: "取消代理设置",
-	Run: func(cmd *cobra.Command, args []string) {
-		t := time.Now()
-		err := sysproxy.DisableProxy(device, onlyActiveDevice)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := audited("disable", func() error {
+			return sysproxy.DisableProxy(device, onlyActiveDevice)
+		})
 		if err != nil {
-			fmt.Println("取消代理设置失败：", err)
-			return
+			return fail("取消代理设置失败", err)
+		}
+		if err := verifyApplied(&sysproxy.ProxyConfig{}); err != nil {
+			return err
 		}
-		fmt.Println("代理设置已取消，耗时：", time.Since(t))
+		return done(cmd, i18n.Sprintf("代理设置已取消，耗时：%v", elapsed()), nil)
 	},
 }
 
+type statusResult struct {
+	*sysproxy.ProxyConfig
+	Profile string `json:"profile,omitempty"`
+}
+
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "查看当前代理设置",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		status, err := sysproxy.QueryProxySettings(device, onlyActiveDevice)
 		if err != nil {
-			fmt.Println("查询代理设置失败：", err)
-			return
+			return fail("查询代理设置失败", err)
+		}
+		result := statusResult{status, matchProfile(status)}
+		if jsonOutput() {
+			return done(cmd, "", result)
 		}
-		statusJSON, err := json.MarshalIndent(status, "", "  ")
+		statusJSON, err := json.MarshalIndent(result, "", "  ")
 		if err != nil {
-			fmt.Println("格式化 JSON 失败：", err)
-			return
+			return fail("格式化 JSON 失败", err)
 		}
-		fmt.Println(string(statusJSON))
+		return done(cmd, string(statusJSON), nil)
 	},
 }
 
 var serverCmd = &cobra.Command{
 	Use:   "server",
 	Short: "启动监听服务",
-	Run: func(cmd *cobra.Command, args []string) {
-		err := sysproxy.Start(listen)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode, err := strconv.ParseUint(socketMode, 8, 32)
 		if err != nil {
-			fmt.Println("启动代理服务失败：", err)
-			return
+			return invalid("无效的套接字权限：%s", socketMode)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		served := make(chan struct{})
+		stopped := make(chan error, 1)
+		go func() {
+			select {
+			case <-ctx.Done():
+			case <-served:
+				stopped <- nil
+				return
+			}
+			// 停止期间再次收到信号时直接退出
+			stop()
+			c, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			stopped <- sysproxy.Stop(c)
+		}()
+
+		err = sysproxy.Serve(sysproxy.ServerOptions{
+			Listen:      listen,
+			IdleTimeout: idleTimeout,
+			SocketMode:  os.FileMode(mode),
+			SocketOwner: socketOwner,
+			SocketGroup: socketGroup,
+			Allow: sysproxy.PeerPolicy{
+				UIDs:        allowUIDs,
+				GIDs:        allowGIDs,
+				Executables: allowExes,
+			},
+			TCP:         tcpListen,
+			TokenFile:   tokenFile,
+			AllowRemote: allowRemote,
+
+			ShutdownTimeout: shutdownTimeout,
+			RestoreOnExit:   restoreOnExit,
+			Ready: func(addrs []string) {
+				emit(i18n.Sprintf("代理服务已启动，监听：%s", strings.Join(addrs, ", ")), map[string]any{"event": "ready", "listen": addrs})
+			},
+			Metrics: metrics,
+		})
+		close(served)
+		if err != nil {
+			return fail("启动代理服务失败", err)
+		}
+		if err := <-stopped; err != nil {
+			return fail("停止代理服务失败", err)
 		}
-		fmt.Println("代理服务已启动")
+		return done(cmd, i18n.T("代理服务已停止"), nil)
 	},
 }
 
@@ -115,12 +200,24 @@ func init() {
 
 	pacCmd.Flags().StringVarP(&pacUrl, "url", "u", "", "pac 地址")
 
-	serverCmd.Flags().StringVarP(&listen, "listen", "l", "/tmp/sparkle-helper.sock", "监听地址")
+	serverCmd.Flags().StringVarP(&listen, "listen", "l", sysproxy.DefaultListenAddr(), "监听地址，Linux 下以 @ 开头时为抽象命名空间套接字")
+	serverCmd.Flags().DurationVar(&idleTimeout, "idle-timeout", 0, "无请求超过该时间后退出，0 表示不退出")
+	serverCmd.Flags().StringVar(&socketMode, "socket-mode", "0600", "套接字文件权限（八进制）")
+	serverCmd.Flags().StringVar(&socketOwner, "socket-owner", "", "套接字文件属主，用户名或 uid")
+	serverCmd.Flags().StringVar(&socketGroup, "socket-group", "", "套接字文件属组，组名或 gid")
+	serverCmd.Flags().IntSliceVar(&allowUIDs, "allow-uid", nil, "允许修改设置的 uid，可重复指定")
+	serverCmd.Flags().IntSliceVar(&allowGIDs, "allow-gid", nil, "允许修改设置的 gid，可重复指定")
+	serverCmd.Flags().StringSliceVar(&allowExes, "allow-exe", nil, "允许修改设置的程序路径，可重复指定")
+	serverCmd.Flags().StringVar(&tcpListen, "tcp", "", "同时监听的 TCP 地址，如 127.0.0.1:9090，请求需携带令牌")
+	serverCmd.Flags().StringVar(&tokenFile, "token-file", sysproxy.DefaultTokenPath(), "令牌文件路径")
+	serverCmd.Flags().BoolVar(&allowRemote, "force", false, "允许 TCP 监听非回环地址")
+	serverCmd.Flags().DurationVar(&shutdownTimeout, "shutdown-timeout", 5*time.Second, "停止时等待进行中请求完成的最长时间")
+	serverCmd.Flags().BoolVar(&restoreOnExit, "restore-on-exit", false, "退出时恢复启动时的代理设置")
+	serverCmd.Flags().BoolVar(&metrics, "metrics", false, "提供 Prometheus 格式的 /metrics 接口")
 }
 
 func main() {
-	if err := cmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	if c, err := cmd.ExecuteC(); err != nil {
+		os.Exit(handleError(c, err))
 	}
 }
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/xishang0128/sysproxy-go/i18n"
+	"github.com/xishang0128/sysproxy-go/sysproxy"
+
+	"github.com/spf13/cobra"
+)
+
+// 退出码
+const (
+	ExitOK           = 0
+	ExitFailed       = 1
+	ExitInvalidInput = 2
+	ExitUnsupported  = 3
+	ExitVerifyFailed = 4
+)
+
+var (
+	output string
+	verify bool
+
+	// processStart 为进程启动的时间，跟踪记录的偏移相对于它计算
+	processStart = time.Now()
+	// startTime 为命令开始执行的时间，PersistentPreRunE 完成设置后更新
+	startTime = processStart
+	// measured 为 measure 记录的操作耗时
+	measured time.Duration
+	// parsed 表示参数已解析完成，之前的错误均来自参数解析
+	parsed bool
+)
+
+type result struct {
+	Command    string       `json:"command"`
+	OK         bool         `json:"ok"`
+	Duration   string       `json:"duration"`
+	DurationMs float64      `json:"duration_ms"`
+	Message    string       `json:"message,omitempty"`
+	Data       any          `json:"data,omitempty"`
+	Error      *resultError `json:"error,omitempty"`
+}
+
+type resultError struct {
+	Code     string `json:"code"`
+	ExitCode int    `json:"exit_code"`
+	Message  string `json:"message"`
+}
+
+// cliError 为命令执行失败，action 描述失败的操作
+type cliError struct {
+	action string
+	err    error
+	code   int
+}
+
+func (e *cliError) Error() string {
+	return i18n.Sprintf("%s：%v", i18n.T(e.action), e.err)
+}
+
+func (e *cliError) Unwrap() error {
+	return e.err
+}
+
+func fail(action string, err error) error {
+	return &cliError{action: action, err: err}
+}
+
+func invalid(format string, args ...any) error {
+	return &cliError{action: "参数无效", err: i18n.Errorf(format, args...), code: ExitInvalidInput}
+}
+
+type verifyError struct {
+	diff []string
+}
+
+func (e *verifyError) Error() string {
+	return i18n.Sprintf("设置后的代理与期望不一致：%s", strings.Join(e.diff, "; "))
+}
+
+// childExit 为子进程的非零退出码，直接作为本进程的退出码
+type childExit struct {
+	code int
+}
+
+func (e *childExit) Error() string {
+	return i18n.Sprintf("命令退出码：%d", e.code)
+}
+
+func jsonOutput() bool {
+	return output == "json"
+}
+
+// measure 执行修改设置的操作并记录耗时，之后 elapsed 返回该耗时
+func measure(fn func() error) error {
+	start := time.Now()
+	err := fn()
+	measured = time.Since(start)
+	return err
+}
+
+// elapsed 返回 measure 记录的操作耗时，没有记录时返回命令开始执行以来的时间
+func elapsed() time.Duration {
+	if measured > 0 {
+		return measured
+	}
+	return time.Since(startTime)
+}
+
+// resultWriter 返回结果的输出位置，run 与 exec 的标准输出属于子进程
+func resultWriter(c *cobra.Command) io.Writer {
+	if c.Annotations["output"] == "stderr" {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+func newResult(c *cobra.Command) result {
+	d := elapsed()
+	return result{
+		Command:    strings.TrimPrefix(c.CommandPath(), c.Root().Name()+" "),
+		Duration:   d.String(),
+		DurationMs: float64(d.Microseconds()) / 1000,
+	}
+}
+
+// done 输出成功结果，文本模式下输出 text，JSON 模式下输出包含 data 的结果对象
+func done(c *cobra.Command, text string, data any) error {
+	if !jsonOutput() {
+		if text != "" {
+			fmt.Fprintln(resultWriter(c), text)
+		}
+		return nil
+	}
+	r := newResult(c)
+	r.OK = true
+	r.Message = text
+	r.Data = data
+	return json.NewEncoder(resultWriter(c)).Encode(r)
+}
+
+// emit 输出持续运行的命令中的事件，JSON 模式下每个事件一行
+func emit(text string, event any) {
+	if jsonOutput() {
+		_ = json.NewEncoder(os.Stdout).Encode(event)
+		return
+	}
+	fmt.Println(time.Now().Format(time.DateTime), text)
+}
+
+func exitCodeOf(err error) int {
+	var (
+		cli   *cliError
+		child *childExit
+		ve    *verifyError
+	)
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &child):
+		return child.code
+	case errors.As(err, &ve):
+		return ExitVerifyFailed
+	case errors.Is(err, sysproxy.ErrUnsupported):
+		return ExitUnsupported
+	case errors.Is(err, sysproxy.ErrInvalidInput):
+		return ExitInvalidInput
+	case errors.As(err, &cli):
+		if cli.code != 0 {
+			return cli.code
+		}
+		return ExitFailed
+	case !parsed:
+		// PersistentPreRunE 之前的错误来自参数解析
+		return ExitInvalidInput
+	default:
+		return ExitFailed
+	}
+}
+
+func errorCode(code int) string {
+	switch code {
+	case ExitInvalidInput:
+		return "invalid_input"
+	case ExitUnsupported:
+		return "unsupported"
+	case ExitVerifyFailed:
+		return "verify_failed"
+	default:
+		return "failed"
+	}
+}
+
+func handleError(c *cobra.Command, err error) int {
+	// 参数校验失败时 PersistentPreRunE 尚未执行
+	_ = setLang()
+	code := exitCodeOf(err)
+
+	var child *childExit
+	if errors.As(err, &child) {
+		if jsonOutput() {
+			r := newResult(c)
+			r.Data = map[string]int{"exit_code": child.code}
+			_ = json.NewEncoder(resultWriter(c)).Encode(r)
+		}
+		return code
+	}
+
+	if jsonOutput() {
+		r := newResult(c)
+		r.Error = &resultError{Code: errorCode(code), ExitCode: code, Message: err.Error()}
+		_ = json.NewEncoder(resultWriter(c)).Encode(r)
+		return code
+	}
+
+	fmt.Fprintln(os.Stderr, err)
+	var cli *cliError
+	if !errors.As(err, &cli) && code == ExitInvalidInput {
+		fmt.Fprintln(os.Stderr, i18n.Sprintf("使用 \"%s --help\" 查看用法", c.CommandPath()))
+	}
+	return code
+}
+
+// verifyApplied 在 --verify 时检查设置是否已生效
+func verifyApplied(desired *sysproxy.ProxyConfig) error {
+	if !verify {
+		return nil
+	}
+	actual, err := sysproxy.QueryProxySettings(device, onlyActiveDevice)
+	if err != nil {
+		return fail("验证代理设置失败", err)
+	}
+	if diff := sysproxy.Drift(desired, actual); len(diff) > 0 {
+		return &verifyError{diff: diff}
+	}
+	return nil
+}
+
+func init() {
+	cmd.PersistentFlags().StringVarP(&output, "output", "o", "text", "输出格式：text、json")
+	cmd.PersistentFlags().BoolVar(&verify, "verify", false, "设置后重新查询以验证是否生效")
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/xishang0128/sysproxy-go/sysproxy"
+)
+
+func TestExitCodeOf(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		parsed bool
+		code   int
+		kind   string
+	}{
+		{"ok", nil, true, ExitOK, "failed"},
+		{"child exit", &childExit{code: 7}, true, 7, "failed"},
+		{"verify", &verifyError{diff: []string{"mode: direct -> proxy"}}, true, ExitVerifyFailed, "verify_failed"},
+		{"unsupported", fail("设置代理失败", sysproxy.ErrUnsupported), true, ExitUnsupported, "unsupported"},
+		{"sysproxy invalid input", fail("设置代理失败", sysproxy.ErrInvalidInput), true, ExitInvalidInput, "invalid_input"},
+		{"invalid", invalid("未知的输出格式：%s", "xml"), true, ExitInvalidInput, "invalid_input"},
+		{"system", fail("设置代理失败", sysproxy.ErrSystem), true, ExitFailed, "failed"},
+		{"unavailable", sysproxy.ErrUnavailable, true, ExitFailed, "failed"},
+		{"conflict", fmt.Errorf("guard: %w", sysproxy.ErrConflict), true, ExitFailed, "failed"},
+		{"forbidden", sysproxy.ErrForbidden, true, ExitFailed, "failed"},
+		{"failed", fail("设置代理失败", errors.New("exit status 1")), true, ExitFailed, "failed"},
+		{"cli error code", &cliError{action: "恢复代理设置失败", err: errors.New("boom"), code: 5}, true, 5, "failed"},
+		{"wrapped verify", fmt.Errorf("run: %w", &verifyError{}), true, ExitVerifyFailed, "verify_failed"},
+		{"unclassified", errors.New("write /dev/stdout: broken pipe"), true, ExitFailed, "failed"},
+		{"argument parsing", errors.New(`unknown flag: --nope`), false, ExitInvalidInput, "invalid_input"},
+	}
+	defer func(p bool) { parsed = p }(parsed)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed = tt.parsed
+			code := exitCodeOf(tt.err)
+			if code != tt.code {
+				t.Errorf("exitCodeOf(%v) = %d, want %d", tt.err, code, tt.code)
+			}
+			if kind := errorCode(code); kind != tt.kind {
+				t.Errorf("errorCode(%d) = %s, want %s", code, kind, tt.kind)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/xishang0128/sysproxy-go/i18n"
+	"github.com/xishang0128/sysproxy-go/sysproxy"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	profilesPath string
+	profile      string
+)
+
+var useCmd = &cobra.Command{
+	Use:   "use <profile>",
+	Short: "应用指定的代理配置",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := loadProfile(args[0])
+		if err != nil {
+			return fail("读取配置失败", err)
+		}
+
+		err = audited("use", func() error {
+			return sysproxy.Apply(p.Config(), device, onlyActiveDevice)
+		})
+		if err != nil {
+			return fail("应用配置失败", err)
+		}
+		if err := verifyApplied(p.Config()); err != nil {
+			return err
+		}
+		return done(cmd, i18n.Sprintf("已应用配置 %s，耗时：%v", args[0], elapsed()), map[string]string{"profile": args[0]})
+	},
+}
+
+var profilesCmd = &cobra.Command{
+	Use:   "profiles",
+	Short: "管理代理配置",
+}
+
+var profilesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出所有代理配置",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := sysproxy.LoadProfiles(profilesPath)
+		if err != nil {
+			return fail("读取配置失败", err)
+		}
+
+		active := ""
+		if current, err := sysproxy.QueryProxySettings(device, onlyActiveDevice); err == nil {
+			active = file.Match(current)
+		}
+		type item struct {
+			Name   string        `json:"name"`
+			Mode   sysproxy.Mode `json:"mode"`
+			Active bool          `json:"active"`
+		}
+		items := []item{}
+		var lines []string
+		for _, name := range file.Names() {
+			mark := " "
+			if name == active {
+				mark = "*"
+			}
+			items = append(items, item{name, file.Profiles[name].Mode, name == active})
+			lines = append(lines, fmt.Sprintf("%s %s\t%s", mark, name, file.Profiles[name].Mode))
+		}
+		return done(cmd, strings.Join(lines, "\n"), items)
+	},
+}
+
+var profilesShowCmd = &cobra.Command{
+	Use:   "show <profile>",
+	Short: "查看代理配置",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := loadProfile(args[0])
+		if err != nil {
+			return fail("读取配置失败", err)
+		}
+		if jsonOutput() {
+			return done(cmd, "", p)
+		}
+		profileJSON, err := json.MarshalIndent(p, "", "  ")
+		if err != nil {
+			return fail("格式化 JSON 失败", err)
+		}
+		return done(cmd, string(profileJSON), nil)
+	},
+}
+
+func loadProfile(name string) (*sysproxy.Profile, error) {
+	file, err := sysproxy.LoadProfiles(profilesPath)
+	if err != nil {
+		return nil, err
+	}
+	return file.Get(name)
+}
+
+// matchProfile 返回与当前设置一致的配置名称，没有配置文件时返回空
+func matchProfile(config *sysproxy.ProxyConfig) string {
+	file, err := sysproxy.LoadProfiles(profilesPath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintln(os.Stderr, i18n.Sprintf("读取配置失败：%v", err))
+		}
+		return ""
+	}
+	return file.Match(config)
+}
+
+func init() {
+	cmd.AddCommand(useCmd)
+	cmd.AddCommand(profilesCmd)
+	profilesCmd.AddCommand(profilesListCmd)
+	profilesCmd.AddCommand(profilesShowCmd)
+
+	cmd.PersistentFlags().StringVar(&profilesPath, "profiles", "", "代理配置文件路径，默认为用户配置目录下的 sysproxy/profiles.json")
+
+	execCmd.Flags().StringVarP(&profile, "profile", "p", "", "使用指定代理配置")
+}
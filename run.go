@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/xishang0128/sysproxy-go/i18n"
+	"github.com/xishang0128/sysproxy-go/sysproxy"
+
+	"github.com/spf13/cobra"
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run [flags] -- command [args...]",
+	Short: "设置代理后运行命令，命令退出后恢复原设置",
+	Args:  cobra.MinimumNArgs(1),
+	// 标准输出属于子进程，结果输出到标准错误
+	Annotations: map[string]string{"output": "stderr"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if server == "" && pacUrl == "" {
+			return invalid("需要指定 --server 或 --url")
+		}
+
+		snapshot, err := sysproxy.QueryProxySettings(device, onlyActiveDevice)
+		if err != nil {
+			return fail("查询代理设置失败", err)
+		}
+
+		desired := sysproxy.NewProxyConfig(server, bypass)
+		if pacUrl != "" {
+			desired = sysproxy.NewPacConfig(pacUrl)
+		}
+
+		// 在应用代理之前开始接收信号，确保任何时候退出都会恢复原设置
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+		defer signal.Stop(signals)
+
+		var runErr error
+		code := 0
+		err = audit("run", func() error {
+			return sysproxy.Apply(desired, device, onlyActiveDevice)
+		})
+		if err != nil {
+			runErr = fail("设置代理失败", err)
+		} else {
+			code = runChild(args, signals)
+		}
+
+		err = audit("run_restore", func() error {
+			return sysproxy.Restore(snapshot, device, onlyActiveDevice)
+		})
+		if err != nil {
+			if runErr != nil {
+				fmt.Fprintln(os.Stderr, runErr)
+			}
+			return &cliError{action: "恢复代理设置失败", err: err, code: code}
+		}
+		if runErr != nil {
+			return runErr
+		}
+		if code != 0 {
+			return &childExit{code: code}
+		}
+		return done(cmd, "", map[string]int{"exit_code": 0})
+	},
+}
+
+func runChild(args []string, signals <-chan os.Signal) int {
+	return runChildEnv(args, nil, signals)
+}
+
+func runChildEnv(args []string, env []string, signals <-chan os.Signal) int {
+	child := exec.Command(args[0], args[1:]...)
+	child.Env = env
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+	if err := child.Start(); err != nil {
+		fmt.Fprintln(os.Stderr, i18n.Sprintf("启动命令失败：%v", err))
+		return 127
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- child.Wait()
+	}()
+
+	for {
+		select {
+		case sig := <-signals:
+			_ = child.Process.Signal(sig)
+		case err := <-done:
+			return exitCode(err)
+		}
+	}
+}
+
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return 1
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return exitErr.ExitCode()
+}
+
+func init() {
+	cmd.AddCommand(runCmd)
+
+	runCmd.Flags().SetInterspersed(false)
+	runCmd.Flags().StringVarP(&server, "server", "s", "", "代理服务器地址")
+	runCmd.Flags().StringVarP(&bypass, "bypass", "b", "", "绕过地址")
+	runCmd.Flags().StringVarP(&pacUrl, "url", "u", "", "pac 地址")
+}
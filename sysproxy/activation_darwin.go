@@ -0,0 +1,53 @@
+//go:build cgo
+
+package sysproxy
+
+/*
+#include <errno.h>
+#include <launch.h>
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// launchdSocketName 为 launchd plist 中 Sockets 下的键名
+const launchdSocketName = "Listeners"
+
+// activationListeners 返回 launchd 按套接字启动时传入的监听套接字
+func activationListeners() ([]net.Listener, error) {
+	name := C.CString(launchdSocketName)
+	defer C.free(unsafe.Pointer(name))
+
+	var fds *C.int
+	var count C.size_t
+	if ret := C.launch_activate_socket(name, &fds, &count); ret != 0 {
+		// 不是由 launchd 启动或 plist 中没有对应的套接字
+		if ret == C.ESRCH || ret == C.ENOENT {
+			return nil, nil
+		}
+		return nil, newError(CodeSystem, "无法获取 launchd 传入的套接字：%v", syscall.Errno(ret))
+	}
+	defer C.free(unsafe.Pointer(fds))
+
+	var listeners []net.Listener
+	for _, fd := range unsafe.Slice(fds, int(count)) {
+		f := os.NewFile(uintptr(fd), "launchd")
+		l, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return nil, newError(CodeSystem, "无法获取 launchd 传入的套接字：%v", err)
+		}
+		logger().Info("socket activated", "source", "launchd", "addr", l.Addr().String())
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
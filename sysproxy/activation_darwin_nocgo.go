@@ -0,0 +1,10 @@
+//go:build darwin && !cgo
+
+package sysproxy
+
+import "net"
+
+// 未启用 cgo 时无法调用 launch_activate_socket
+func activationListeners() ([]net.Listener, error) {
+	return nil, nil
+}
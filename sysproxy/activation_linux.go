@@ -0,0 +1,44 @@
+package sysproxy
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// systemd 传入的套接字从 3 开始编号
+const listenFdsStart = 3
+
+// activationListeners 返回 systemd 按套接字启动时通过 LISTEN_FDS 传入的监听套接字
+func activationListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+	// 避免子进程误用
+	_ = os.Unsetenv("LISTEN_PID")
+	_ = os.Unsetenv("LISTEN_FDS")
+	_ = os.Unsetenv("LISTEN_FDNAMES")
+
+	var listeners []net.Listener
+	for fd := listenFdsStart; fd < listenFdsStart+n; fd++ {
+		syscall.CloseOnExec(fd)
+		f := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		l, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return nil, newError(CodeSystem, "无法使用 systemd 传入的套接字：%v", err)
+		}
+		logger().Info("socket activated", "source", "systemd", "addr", l.Addr().String())
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
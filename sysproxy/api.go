@@ -0,0 +1,272 @@
+package sysproxy
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// APIVersion 为 /v1 接口的版本，接口发生不兼容的变化时递增
+const APIVersion = 1
+
+// 以下为 /v1 接口的参数与返回类型，参数既可以放在 JSON 请求体中，也可以作为同名查询参数传递
+
+type TargetParams struct {
+	Device           string `json:"device,omitempty"`
+	OnlyActiveDevice bool   `json:"only_active_device,omitempty"`
+}
+
+type ProxyParams struct {
+	// Server 为所有协议使用同一地址，Servers 按 http、https、socks、ftp 分别指定
+	Server  string            `json:"server,omitempty"`
+	Servers map[string]string `json:"servers,omitempty"`
+	Bypass  string            `json:"bypass,omitempty"`
+	// Force 为 true 时停止正在运行的保持或看门狗，否则返回冲突
+	Force bool `json:"force,omitempty"`
+	TargetParams
+}
+
+type PacParams struct {
+	URL   string `json:"url,omitempty"`
+	Force bool   `json:"force,omitempty"`
+	TargetParams
+}
+
+type DisableParams struct {
+	Force bool `json:"force,omitempty"`
+	TargetParams
+}
+
+type GuardParams struct {
+	// 指定 URL 时保持 PAC 设置，否则保持 Server 代理设置
+	Server string `json:"server,omitempty"`
+	Bypass string `json:"bypass,omitempty"`
+	URL    string `json:"url,omitempty"`
+	Force  bool   `json:"force,omitempty"`
+	TargetParams
+}
+
+type WatchdogParams struct {
+	// Server 为空时使用当前的代理设置
+	Server string `json:"server,omitempty"`
+	Bypass string `json:"bypass,omitempty"`
+	// Probe 可选 tcp、http、socks5，Interval 与 Timeout 为 Go 时长格式，如 5s
+	Probe          string `json:"probe,omitempty"`
+	Interval       string `json:"interval,omitempty"`
+	Timeout        string `json:"timeout,omitempty"`
+	Failures       int    `json:"failures,omitempty"`
+	FallbackServer string `json:"fallback_server,omitempty"`
+	FallbackURL    string `json:"fallback_url,omitempty"`
+	Force          bool   `json:"force,omitempty"`
+	TargetParams
+}
+
+const (
+	LeaseRestore = "restore"
+	LeaseDisable = "disable"
+)
+
+// LeaseParams 创建或续期租约，同一 Owner 再次请求时续期并保留原有快照
+type LeaseParams struct {
+	Owner string `json:"owner,omitempty"`
+	// TTL 为 Go 时长格式，超过该时间未续期时租约过期，Hold 为 true 时可以为空
+	TTL string `json:"ttl,omitempty"`
+	// OnExpire 为 restore（默认，恢复租约开始前的设置）或 disable
+	OnExpire string `json:"on_expire,omitempty"`
+	// Hold 为 true 时保持响应连接，连接断开时租约立即过期
+	Hold bool `json:"hold,omitempty"`
+	TargetParams
+}
+
+type LeaseHeartbeat struct {
+	Owner string `json:"owner,omitempty"`
+}
+
+type LeaseRelease struct {
+	Owner string `json:"owner,omitempty"`
+	// Expire 为 true 时按 OnExpire 恢复设置，否则只释放租约
+	Expire bool `json:"expire,omitempty"`
+}
+
+type LeaseInfo struct {
+	Owner    string     `json:"owner"`
+	TTL      string     `json:"ttl,omitempty"`
+	OnExpire string     `json:"on_expire"`
+	Hold     bool       `json:"hold,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	// Snapshot 为第一个租约开始前的设置，同一目标上的租约共享
+	Snapshot *ProxyConfig `json:"snapshot"`
+	TargetParams
+}
+
+const (
+	EventSourceAPI    = "api"
+	EventSourceSystem = "system"
+)
+
+// StateEvent 为 /v1/events 推送的事件，snapshot 事件只包含 New，
+// change 事件的 Source 为 api 时由接口修改引起，为 system 时由其他程序、保持或看门狗引起
+type StateEvent struct {
+	Time   time.Time    `json:"time"`
+	Source string       `json:"source,omitempty"`
+	Old    *ProxyConfig `json:"old,omitempty"`
+	New    *ProxyConfig `json:"new"`
+}
+
+// ServerInfo 为 /v1/ping 的返回
+type ServerInfo struct {
+	Version string `json:"version"`
+	API     int    `json:"api"`
+}
+
+type GuardInfo struct {
+	Active bool         `json:"active"`
+	Guard  *GuardStatus `json:"guard,omitempty"`
+}
+
+type WatchdogInfo struct {
+	Active   bool            `json:"active"`
+	Watchdog *WatchdogStatus `json:"watchdog,omitempty"`
+}
+
+// APIError 为 /v1 接口的错误响应，Fields 按字段名给出参数校验错误
+type APIError struct {
+	Code    ErrorCode         `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+func (p *ProxyParams) Validate() error {
+	errs := FieldErrors{}
+	if p.Server == "" && len(p.Servers) == 0 {
+		errs.add("server", "需要指定 server 或 servers")
+	}
+	if p.Server != "" && !validServer(p.Server) {
+		errs.add("server", "无效的代理地址：%s", p.Server)
+	}
+	for protocol, server := range p.Servers {
+		field := "servers[" + protocol + "]"
+		if _, ok := profileServerKeys[protocol]; !ok {
+			errs.add(field, "未知的协议：%s", protocol)
+		} else if !validServer(server) {
+			errs.add(field, "无效的代理地址：%s", server)
+		}
+	}
+	return errs.err()
+}
+
+func (p *ProxyParams) Config() *ProxyConfig {
+	profile := Profile{Mode: ModeProxy, Server: p.Server, Servers: p.Servers, Bypass: p.Bypass}
+	return profile.Config()
+}
+
+func (p *PacParams) Validate() error {
+	errs := FieldErrors{}
+	switch {
+	case p.URL == "":
+		errs.add("url", "需要指定 url")
+	case !validURL(p.URL):
+		errs.add("url", "无效的 URL：%s", p.URL)
+	}
+	return errs.err()
+}
+
+func (p *GuardParams) Validate() error {
+	errs := FieldErrors{}
+	switch {
+	case p.Server == "" && p.URL == "":
+		errs.add("server", "需要指定 server 或 url")
+	case p.Server != "" && p.URL != "":
+		errs.add("url", "server 与 url 只能指定一个")
+	case p.Server != "" && !validServer(p.Server):
+		errs.add("server", "无效的代理地址：%s", p.Server)
+	case p.URL != "" && !validURL(p.URL):
+		errs.add("url", "无效的 URL：%s", p.URL)
+	}
+	return errs.err()
+}
+
+func (p *GuardParams) Config() *ProxyConfig {
+	if p.URL != "" {
+		return NewPacConfig(p.URL)
+	}
+	return NewProxyConfig(p.Server, p.Bypass)
+}
+
+func (p *WatchdogParams) Validate() error {
+	errs := FieldErrors{}
+	if p.Server != "" && !validServer(p.Server) {
+		errs.add("server", "无效的代理地址：%s", p.Server)
+	}
+	if _, err := probeFunc(p.Probe); err != nil {
+		errs.add("probe", "未知的探测方式：%s", p.Probe)
+	}
+	for field, value := range map[string]string{"interval": p.Interval, "timeout": p.Timeout} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			errs.add(field, "无效的时间间隔：%s", value)
+		}
+	}
+	if p.Failures < 0 {
+		errs.add("failures", "失败次数不能为负数")
+	}
+	switch {
+	case p.FallbackServer != "" && p.FallbackURL != "":
+		errs.add("fallback_url", "fallback_server 与 fallback_url 只能指定一个")
+	case p.FallbackServer != "" && !validServer(p.FallbackServer):
+		errs.add("fallback_server", "无效的代理地址：%s", p.FallbackServer)
+	case p.FallbackURL != "" && !validURL(p.FallbackURL):
+		errs.add("fallback_url", "无效的 URL：%s", p.FallbackURL)
+	}
+	return errs.err()
+}
+
+func (p *LeaseParams) Validate() error {
+	errs := FieldErrors{}
+	if p.Owner == "" {
+		errs.add("owner", "需要指定 owner")
+	}
+	switch {
+	case p.TTL == "" && !p.Hold:
+		errs.add("ttl", "需要指定 ttl 或 hold")
+	case p.TTL != "":
+		if d, err := time.ParseDuration(p.TTL); err != nil || d <= 0 {
+			errs.add("ttl", "无效的时间间隔：%s", p.TTL)
+		}
+	}
+	switch p.OnExpire {
+	case "", LeaseRestore, LeaseDisable:
+	default:
+		errs.add("on_expire", "未知的过期操作：%s", p.OnExpire)
+	}
+	return errs.err()
+}
+
+func (p *LeaseHeartbeat) Validate() error {
+	errs := FieldErrors{}
+	if p.Owner == "" {
+		errs.add("owner", "需要指定 owner")
+	}
+	return errs.err()
+}
+
+func (p *LeaseRelease) Validate() error {
+	errs := FieldErrors{}
+	if p.Owner == "" {
+		errs.add("owner", "需要指定 owner")
+	}
+	return errs.err()
+}
+
+func validServer(server string) bool {
+	addr := ParseServerString(stripScheme(server))
+	port, err := strconv.Atoi(addr.port)
+	return addr.host != "" && err == nil && port > 0 && port <= 65535
+}
+
+func validURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && (u.Host != "" || u.Scheme == "file")
+}
@@ -0,0 +1,90 @@
+package sysproxy
+
+import (
+	"fmt"
+	"strings"
+)
+
+func NewProxyConfig(server, bypass string) *ProxyConfig {
+	config := &ProxyConfig{}
+	config.Proxy.Enable = true
+	config.Proxy.SameForAll = true
+	config.Proxy.Servers = map[string]string{
+		"http_server":  server,
+		"https_server": server,
+		"socks_server": server,
+	}
+	config.Proxy.Bypass = bypass
+	return config
+}
+
+func NewPacConfig(pacUrl string) *ProxyConfig {
+	config := &ProxyConfig{}
+	config.PAC.Enable = true
+	config.PAC.URL = pacUrl
+	return config
+}
+
+func Apply(config *ProxyConfig, device string, onlyActiveDevice bool) error {
+	logger().Debug("apply proxy config", "mode", config.Mode(), "device", device, "only_active_device", onlyActiveDevice)
+	switch config.Mode() {
+	case ModePAC:
+		return SetPac(config.PAC.URL, device, onlyActiveDevice)
+	case ModeProxy:
+		if !config.Proxy.SameForAll {
+			return setProxyServers(config, device, onlyActiveDevice)
+		}
+		return SetProxy(config.Server(), config.Proxy.Bypass, device, onlyActiveDevice)
+	default:
+		return DisableProxy(device, onlyActiveDevice)
+	}
+}
+
+// Restore 写入 config 中的全部设置，包括未启用的代理地址、绕过列表与 PAC 地址，
+// 用于恢复 QueryProxySettings 保存的快照；Apply 只写入当前模式用到的字段
+func Restore(config *ProxyConfig, device string, onlyActiveDevice bool) error {
+	logger().Debug("restore proxy config", "mode", config.Mode(), "device", device, "only_active_device", onlyActiveDevice)
+	return restoreProxy(config, device, onlyActiveDevice)
+}
+
+// Drift 返回实际设置与期望设置之间的差异，期望中留空的字段不参与比较
+func Drift(desired, actual *ProxyConfig) []string {
+	var diff []string
+	add := func(name, want, got string) {
+		if want != got {
+			diff = append(diff, fmt.Sprintf("%s: %s -> %s", name, got, want))
+		}
+	}
+
+	add("mode", string(desired.Mode()), string(actual.Mode()))
+	switch desired.Mode() {
+	case ModeProxy:
+		if desired.Proxy.SameForAll {
+			if desired.Server() != "" {
+				add("server", desired.Server(), actual.Server())
+			}
+		} else {
+			for _, key := range serverKeys(desired, actual) {
+				add(key, desired.Proxy.Servers[key], actual.Proxy.Servers[key])
+			}
+		}
+		if desired.Proxy.Bypass != "" {
+			add("bypass", normalizeBypass(desired.Proxy.Bypass), normalizeBypass(actual.Proxy.Bypass))
+		}
+	case ModePAC:
+		if desired.PAC.URL != "" {
+			add("pac", desired.PAC.URL, actual.PAC.URL)
+		}
+	}
+	return diff
+}
+
+func normalizeBypass(bypass string) string {
+	items := strings.FieldsFunc(bypass, func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n'
+	})
+	for i, item := range items {
+		items[i] = cleanOutput(item)
+	}
+	return strings.Join(items, ",")
+}
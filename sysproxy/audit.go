@@ -0,0 +1,243 @@
+package sysproxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	AuditSourceServer = "server"
+	AuditSourceCLI    = "cli"
+)
+
+// AuditRecord 为审计日志中的一条记录，记录一次修改代理设置的操作
+type AuditRecord struct {
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"`
+	// Operation 为 proxy、pac、disable、guard_start 等操作名
+	Operation string `json:"operation"`
+	Device    string `json:"device,omitempty"`
+	// Callers 为发起操作的调用方，服务合并多个请求时有多个，服务自身发起的操作为空
+	Callers  []AuditCaller `json:"callers,omitempty"`
+	Before   *ProxyConfig  `json:"before,omitempty"`
+	After    *ProxyConfig  `json:"after,omitempty"`
+	Duration time.Duration `json:"duration"`
+	// Result 为 ok 或错误码
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// AuditCaller 为调用方的身份，unix 套接字对端记录进程信息，TCP 对端只记录地址
+type AuditCaller struct {
+	*PeerCred
+	Remote string `json:"remote,omitempty"`
+}
+
+// AuditFilter 筛选审计记录，零值匹配所有记录
+type AuditFilter struct {
+	Since      time.Time
+	Until      time.Time
+	Operations []string
+}
+
+func (f *AuditFilter) Match(r AuditRecord) bool {
+	if !f.Since.IsZero() && r.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && r.Time.After(f.Until) {
+		return false
+	}
+	return len(f.Operations) == 0 || slices.Contains(f.Operations, r.Operation)
+}
+
+// DefaultAuditPath 返回默认的审计日志文件
+func DefaultAuditPath() string {
+	var dir string
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "windows":
+		dir = os.Getenv("LocalAppData")
+	case "darwin":
+		if home != "" {
+			dir = filepath.Join(home, "Library", "Logs")
+		}
+	default:
+		if dir = os.Getenv("XDG_STATE_HOME"); dir == "" && home != "" {
+			dir = filepath.Join(home, ".local", "state")
+		}
+	}
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "sysproxy", "audit.jsonl")
+}
+
+// AuditLog 以 JSON 行追加审计记录，文件超过 MaxSize 后依次轮转为 Path.1 至 Path.MaxBackups
+type AuditLog struct {
+	Path string
+	// MaxSize 为 0 时为 10 MiB
+	MaxSize int64
+	// MaxBackups 为 0 时保留 3 个轮转的文件
+	MaxBackups int
+
+	mu sync.Mutex
+	f  *os.File
+}
+
+func (l *AuditLog) Write(r AuditRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.open(); err != nil {
+		return err
+	}
+	if info, err := l.f.Stat(); err == nil && info.Size() > 0 && info.Size()+int64(len(data)) > l.maxSize() {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	_, err = l.f.Write(data)
+	return err
+}
+
+func (l *AuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+// open 打开日志文件，文件已被其他进程轮转时重新打开
+func (l *AuditLog) open() error {
+	if l.f != nil {
+		current, err := l.f.Stat()
+		disk, diskErr := os.Stat(l.Path)
+		if err == nil && diskErr == nil && os.SameFile(current, disk) {
+			return nil
+		}
+		_ = l.f.Close()
+		l.f = nil
+	}
+	if err := os.MkdirAll(filepath.Dir(l.Path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	l.f = f
+	return nil
+}
+
+func (l *AuditLog) rotate() error {
+	_ = l.f.Close()
+	l.f = nil
+	for i := l.maxBackups() - 1; i >= 1; i-- {
+		if err := os.Rename(rotatedPath(l.Path, i), rotatedPath(l.Path, i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(l.Path, rotatedPath(l.Path, 1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return l.open()
+}
+
+func (l *AuditLog) maxSize() int64 {
+	if l.MaxSize > 0 {
+		return l.MaxSize
+	}
+	return 10 << 20
+}
+
+func (l *AuditLog) maxBackups() int {
+	if l.MaxBackups > 0 {
+		return l.MaxBackups
+	}
+	return 3
+}
+
+func rotatedPath(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
+
+// ReadAuditLog 按时间顺序读取 path 及其轮转文件中符合条件的记录，无法解析的行被跳过
+func ReadAuditLog(path string, filter AuditFilter) ([]AuditRecord, error) {
+	var files []string
+	for i := 1; ; i++ {
+		if _, err := os.Stat(rotatedPath(path, i)); err != nil {
+			break
+		}
+		files = append(files, rotatedPath(path, i))
+	}
+	slices.Reverse(files)
+	files = append(files, path)
+
+	var records []AuditRecord
+	for _, file := range files {
+		f, err := os.Open(file)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			var r AuditRecord
+			if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+				continue
+			}
+			if filter.Match(r) {
+				records = append(records, r)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+var auditLog atomic.Pointer[AuditLog]
+
+// SetAuditLog 设置服务记录修改的审计日志，为 nil 时不记录
+func SetAuditLog(l *AuditLog) {
+	auditLog.Store(l)
+}
+
+func auditing() bool {
+	return auditLog.Load() != nil
+}
+
+// writeAudit 写入审计记录，写入失败只记录日志，不影响操作本身
+func writeAudit(r AuditRecord) {
+	l := auditLog.Load()
+	if l == nil {
+		return
+	}
+	if err := l.Write(r); err != nil {
+		logger().Warn("write audit log failed", "path", l.Path, "error", err)
+	}
+}
@@ -0,0 +1,155 @@
+package sysproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var auditBase = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func auditRecord(i int, operation string) AuditRecord {
+	return AuditRecord{
+		Time:      auditBase.Add(time.Duration(i) * time.Minute),
+		Source:    AuditSourceServer,
+		Operation: operation,
+		Result:    "ok",
+	}
+}
+
+func operations(records []AuditRecord) []string {
+	var ops []string
+	for _, r := range records {
+		ops = append(ops, r.Operation)
+	}
+	return ops
+}
+
+func TestAuditLogRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sysproxy", "audit.jsonl")
+	line, err := json.Marshal(auditRecord(0, "op0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 每个文件恰好容纳两条记录
+	l := &AuditLog{Path: path, MaxSize: 2 * int64(len(line)+1), MaxBackups: 2}
+	defer l.Close()
+	for i := 1; i <= 7; i++ {
+		if err := l.Write(auditRecord(i, fmt.Sprintf("op%d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := map[string][]string{
+		path:                 {"op7"},
+		rotatedPath(path, 1): {"op5", "op6"},
+		rotatedPath(path, 2): {"op3", "op4"},
+	}
+	for file, want := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() > l.MaxSize {
+			t.Errorf("%s is %d bytes, want at most %d", file, info.Size(), l.MaxSize)
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+			var r AuditRecord
+			if err := json.Unmarshal([]byte(line), &r); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, r.Operation)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s = %v, want %v", file, got, want)
+		}
+	}
+	if _, err := os.Stat(rotatedPath(path, 3)); !os.IsNotExist(err) {
+		t.Errorf("%s exists beyond MaxBackups", rotatedPath(path, 3))
+	}
+
+	// 依次读取轮转文件与当前文件
+	records, err := ReadAuditLog(path, AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := operations(records), []string{"op3", "op4", "op5", "op6", "op7"}; !slices.Equal(got, want) {
+		t.Errorf("ReadAuditLog = %v, want %v", got, want)
+	}
+}
+
+func TestAuditLogReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l := &AuditLog{Path: path}
+	defer l.Close()
+	if err := l.Write(auditRecord(1, "op1")); err != nil {
+		t.Fatal(err)
+	}
+	// 其他进程轮转文件后写入新的文件
+	if err := os.Rename(path, rotatedPath(path, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Write(auditRecord(2, "op2")); err != nil {
+		t.Fatal(err)
+	}
+	records, err := ReadAuditLog(path, AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := operations(records), []string{"op1", "op2"}; !slices.Equal(got, want) {
+		t.Errorf("ReadAuditLog = %v, want %v", got, want)
+	}
+}
+
+func TestReadAuditLogFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l := &AuditLog{Path: path}
+	for i, op := range []string{"proxy", "pac", "disable", "proxy", "guard_start"} {
+		if err := l.Write(auditRecord(i, op)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.Close()
+	// 无法解析的行被跳过
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("not json\n")
+	f.Close()
+
+	tests := []struct {
+		name   string
+		filter AuditFilter
+		want   []string
+	}{
+		{"all", AuditFilter{}, []string{"proxy", "pac", "disable", "proxy", "guard_start"}},
+		{"since", AuditFilter{Since: auditBase.Add(3 * time.Minute)}, []string{"proxy", "guard_start"}},
+		{"until", AuditFilter{Until: auditBase.Add(time.Minute)}, []string{"proxy", "pac"}},
+		{"range", AuditFilter{Since: auditBase.Add(time.Minute), Until: auditBase.Add(3 * time.Minute)}, []string{"pac", "disable", "proxy"}},
+		{"operations", AuditFilter{Operations: []string{"proxy", "disable"}}, []string{"proxy", "disable", "proxy"}},
+		{"operations and range", AuditFilter{Since: auditBase.Add(time.Minute), Operations: []string{"proxy"}}, []string{"proxy"}},
+		{"none", AuditFilter{Operations: []string{"restore"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := ReadAuditLog(path, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := operations(records); !slices.Equal(got, tt.want) {
+				t.Errorf("ReadAuditLog = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package sysproxy
+
+// Backend 读取与修改代理设置，服务、保持与看门狗通过它访问系统，测试时可替换为假的实现
+type Backend interface {
+	Query(device string, onlyActiveDevice bool) (*ProxyConfig, error)
+	Apply(config *ProxyConfig, device string, onlyActiveDevice bool) error
+	// Restore 写入完整的快照，见 Restore
+	Restore(config *ProxyConfig, device string, onlyActiveDevice bool) error
+}
+
+// SystemBackend 修改当前系统的代理设置
+type SystemBackend struct{}
+
+func (SystemBackend) Query(device string, onlyActiveDevice bool) (*ProxyConfig, error) {
+	return QueryProxySettings(device, onlyActiveDevice)
+}
+
+func (SystemBackend) Apply(config *ProxyConfig, device string, onlyActiveDevice bool) error {
+	return Apply(config, device, onlyActiveDevice)
+}
+
+func (SystemBackend) Restore(config *ProxyConfig, device string, onlyActiveDevice bool) error {
+	return Restore(config, device, onlyActiveDevice)
+}
+
+// orSystem 在 b 为 nil 时返回 SystemBackend
+func orSystem(b Backend) Backend {
+	if b == nil {
+		return SystemBackend{}
+	}
+	return b
+}
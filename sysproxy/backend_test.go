@@ -0,0 +1,84 @@
+package sysproxy
+
+import (
+	"sync"
+	"testing"
+)
+
+// memoryBackend 在内存中保存设置，代替系统的代理设置
+type memoryBackend struct {
+	mu     sync.Mutex
+	config *ProxyConfig
+	// rival 非 nil 时模拟与保持争夺的程序，应用后的设置只能被查询到一次，之后被改为 rival
+	rival   *ProxyConfig
+	applied bool
+	// err 非 nil 时修改失败
+	err      error
+	applies  []*ProxyConfig
+	restores []*ProxyConfig
+}
+
+func (b *memoryBackend) Query(_ string, _ bool) (*ProxyConfig, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	config := b.config
+	if b.rival != nil && b.applied {
+		b.config = b.rival
+		b.applied = false
+	}
+	return config, nil
+}
+
+func (b *memoryBackend) Apply(config *ProxyConfig, _ string, _ bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return b.err
+	}
+	b.config = config
+	b.applied = true
+	b.applies = append(b.applies, config)
+	return nil
+}
+
+func (b *memoryBackend) Restore(config *ProxyConfig, _ string, _ bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return b.err
+	}
+	b.config = config
+	b.applied = true
+	b.restores = append(b.restores, config)
+	return nil
+}
+
+// set 模拟其他程序修改设置
+func (b *memoryBackend) set(config *ProxyConfig) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.config = config
+}
+
+func (b *memoryBackend) setErr(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.err = err
+}
+
+func (b *memoryBackend) current() *ProxyConfig {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.config
+}
+
+func (b *memoryBackend) counts() (applies, restores int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.applies), len(b.restores)
+}
+
+// pollOnly 使 watchDevice 只通过轮询查询设置，不监听当前桌面的设置来源
+func pollOnly(t *testing.T) {
+	t.Setenv("XDG_CURRENT_DESKTOP", "")
+}
@@ -0,0 +1,210 @@
+// Package client 为 sysproxy server 的 /v1 接口提供类型化的客户端
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/xishang0128/sysproxy-go/i18n"
+	"github.com/xishang0128/sysproxy-go/sysproxy"
+)
+
+// Client 通过 unix 套接字调用 sysproxy server，连接失败时在 ctx 结束前重试，
+// 以便在服务由 systemd 或 launchd 按需启动时等待其就绪
+type Client struct {
+	http  *http.Client
+	base  string
+	token string
+}
+
+// Option 修改 Client 的设置
+type Option func(*Client)
+
+// WithToken 设置通过 TCP 连接时使用的令牌
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithTCP 改为连接 TCP 地址，如 127.0.0.1:9090，需要同时指定 WithToken
+func WithTCP(addr string) Option {
+	return func(c *Client) {
+		c.http.Transport.(*http.Transport).DialContext = retryDial("tcp", addr)
+	}
+}
+
+// New 创建客户端但不连接服务，socketPath 在 Linux 下以 @ 开头时为抽象命名空间套接字
+func New(socketPath string, opts ...Option) *Client {
+	c := &Client{
+		http: &http.Client{
+			Transport: &http.Transport{
+				DialContext:       retryDial("unix", socketPath),
+				DisableKeepAlives: true,
+			},
+		},
+		base: "http://sysproxy/v1",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Dial 创建客户端并等待服务就绪，服务的接口版本与客户端不一致时返回错误
+func Dial(ctx context.Context, socketPath string, opts ...Option) (*Client, error) {
+	c := New(socketPath, opts...)
+	info, err := c.Ping(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if info.API != sysproxy.APIVersion {
+		return nil, i18n.Errorf("服务的接口版本为 %d（%s），客户端需要 %d", info.API, info.Version, sysproxy.APIVersion)
+	}
+	return c, nil
+}
+
+// retryDial 在套接字尚不存在或拒绝连接时重试，此时请求尚未发出，重试对任何请求都是安全的
+func retryDial(network, addr string) func(ctx context.Context, _, _ string) (net.Conn, error) {
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var dialer net.Dialer
+		wait := 50 * time.Millisecond
+		for {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err == nil || !(errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.ECONNREFUSED)) {
+				return conn, err
+			}
+			select {
+			case <-ctx.Done():
+				return nil, err
+			case <-time.After(wait):
+			}
+			wait = min(wait*2, time.Second)
+		}
+	}
+}
+
+// Ping 检查服务是否可用并返回服务版本
+func (c *Client) Ping(ctx context.Context) (*sysproxy.ServerInfo, error) {
+	info := &sysproxy.ServerInfo{}
+	return info, c.do(ctx, http.MethodGet, "/ping", nil, info)
+}
+
+func (c *Client) Status(ctx context.Context, target sysproxy.TargetParams) (*sysproxy.ProxyConfig, error) {
+	config := &sysproxy.ProxyConfig{}
+	return config, c.do(ctx, http.MethodGet, "/status"+targetQuery(target), nil, config)
+}
+
+// SetProxy 设置代理并返回最终应用的设置，保持或看门狗运行时需指定 Force，否则返回 sysproxy.ErrConflict
+func (c *Client) SetProxy(ctx context.Context, p sysproxy.ProxyParams) (*sysproxy.ProxyConfig, error) {
+	config := &sysproxy.ProxyConfig{}
+	return config, c.do(ctx, http.MethodPost, "/proxy", p, config)
+}
+
+func (c *Client) SetPac(ctx context.Context, p sysproxy.PacParams) (*sysproxy.ProxyConfig, error) {
+	config := &sysproxy.ProxyConfig{}
+	return config, c.do(ctx, http.MethodPost, "/pac", p, config)
+}
+
+func (c *Client) Disable(ctx context.Context, p sysproxy.DisableParams) (*sysproxy.ProxyConfig, error) {
+	config := &sysproxy.ProxyConfig{}
+	return config, c.do(ctx, http.MethodPost, "/disable", p, config)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, result any) error {
+	resp, err := c.send(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if result == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return i18n.Errorf("解析响应失败：%w", err)
+	}
+	return nil
+}
+
+// send 发送请求，状态码不为 2xx 时返回 *Error
+func (c *Client) send(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	return nil, readError(resp)
+}
+
+func readError(resp *http.Response) error {
+	e := &Error{Status: resp.StatusCode}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if err := json.Unmarshal(data, &e.APIError); err != nil || e.Code == "" {
+		// 旧版本的服务没有 /v1 接口，返回纯文本的 404
+		e.Code = sysproxy.CodeUnsupported
+		e.Message = i18n.Sprintf("服务返回 %s：%s", resp.Status, bytes.TrimSpace(data))
+	}
+	return e
+}
+
+func targetQuery(target sysproxy.TargetParams) string {
+	query := url.Values{}
+	if target.Device != "" {
+		query.Set("device", target.Device)
+	}
+	if target.OnlyActiveDevice {
+		query.Set("only_active_device", "true")
+	}
+	if len(query) == 0 {
+		return ""
+	}
+	return "?" + query.Encode()
+}
+
+// Error 为服务返回的错误，可通过 errors.Is 与 sysproxy.ErrConflict 等比较
+type Error struct {
+	Status int
+	sysproxy.APIError
+}
+
+func (e *Error) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%s %v", e.Message, e.Fields)
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*sysproxy.Error)
+	return ok && t.Code == e.Code
+}
@@ -0,0 +1,318 @@
+//go:build darwin || linux
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xishang0128/sysproxy-go/sysproxy"
+)
+
+// fakeBackend 在内存中保存设置，代替系统的代理设置
+type fakeBackend struct {
+	mu       sync.Mutex
+	config   *sysproxy.ProxyConfig
+	restored []*sysproxy.ProxyConfig
+}
+
+func (b *fakeBackend) Query(_ string, _ bool) (*sysproxy.ProxyConfig, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return clone(b.config), nil
+}
+
+func (b *fakeBackend) Apply(config *sysproxy.ProxyConfig, _ string, _ bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.config = clone(config)
+	return nil
+}
+
+func (b *fakeBackend) Restore(config *sysproxy.ProxyConfig, _ string, _ bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.config = clone(config)
+	b.restored = append(b.restored, clone(config))
+	return nil
+}
+
+func (b *fakeBackend) current() *sysproxy.ProxyConfig {
+	config, _ := b.Query("", false)
+	return config
+}
+
+func (b *fakeBackend) restores() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.restored)
+}
+
+func clone(config *sysproxy.ProxyConfig) *sysproxy.ProxyConfig {
+	data, _ := json.Marshal(config)
+	c := &sysproxy.ProxyConfig{}
+	_ = json.Unmarshal(data, c)
+	return c
+}
+
+// socketPath 返回临时目录中的套接字路径，t.TempDir 的路径可能超过 unix 套接字的长度限制
+func socketPath(t *testing.T) string {
+	dir, err := os.MkdirTemp("", "sysproxy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "s.sock")
+}
+
+// startServer 在 socket 上启动使用 b 的服务，测试结束时停止
+func startServer(t *testing.T, socket string, b sysproxy.Backend) {
+	ready := make(chan struct{})
+	errs := make(chan error, 1)
+	go func() {
+		errs <- sysproxy.Serve(sysproxy.ServerOptions{
+			Listen:  socket,
+			Backend: b,
+			Ready:   func([]string) { close(ready) },
+		})
+	}()
+	select {
+	case <-ready:
+	case err := <-errs:
+		t.Fatalf("Serve: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := sysproxy.Stop(context.Background()); err != nil {
+			t.Errorf("Stop: %v", err)
+		}
+		<-errs
+	})
+}
+
+func dial(t *testing.T, b *fakeBackend) *Client {
+	socket := socketPath(t)
+	startServer(t, socket, b)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := Dial(ctx, socket)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	return c
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestPing(t *testing.T) {
+	c := dial(t, &fakeBackend{config: &sysproxy.ProxyConfig{}})
+	info, err := c.Ping(testContext(t))
+	if err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if info.API != sysproxy.APIVersion || info.Version != sysproxy.Version {
+		t.Errorf("Ping = %+v, want api %d version %s", info, sysproxy.APIVersion, sysproxy.Version)
+	}
+}
+
+func TestDialVersionMismatch(t *testing.T) {
+	socket := socketPath(t)
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(sysproxy.ServerInfo{Version: "future", API: sysproxy.APIVersion + 1})
+	})}
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+
+	c, err := Dial(testContext(t), socket)
+	if err == nil {
+		t.Fatalf("Dial succeeded with API %d, want error", sysproxy.APIVersion+1)
+	}
+	if c != nil {
+		t.Errorf("Dial returned a client along with error %v", err)
+	}
+}
+
+func TestDialRetriesWhileStarting(t *testing.T) {
+	socket := socketPath(t)
+	dialed := make(chan error, 1)
+	go func() {
+		_, err := Dial(testContext(t), socket)
+		dialed <- err
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	select {
+	case err := <-dialed:
+		t.Fatalf("Dial returned before the server started: %v", err)
+	default:
+	}
+	startServer(t, socket, &fakeBackend{config: &sysproxy.ProxyConfig{}})
+	if err := <-dialed; err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+}
+
+func TestSetAndStatus(t *testing.T) {
+	b := &fakeBackend{config: &sysproxy.ProxyConfig{}}
+	c := dial(t, b)
+	ctx := testContext(t)
+
+	config, err := c.SetProxy(ctx, sysproxy.ProxyParams{Server: "127.0.0.1:7890", Bypass: "localhost"})
+	if err != nil {
+		t.Fatalf("SetProxy: %v", err)
+	}
+	if config.Mode() != sysproxy.ModeProxy || config.Server() != "127.0.0.1:7890" {
+		t.Errorf("SetProxy = %+v, want proxy 127.0.0.1:7890", config)
+	}
+	status, err := c.Status(ctx, sysproxy.TargetParams{})
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if !status.Equal(b.current()) || status.Proxy.Bypass != "localhost" {
+		t.Errorf("Status = %+v, want %+v", status, b.current())
+	}
+
+	if _, err := c.SetPac(ctx, sysproxy.PacParams{URL: "http://127.0.0.1/proxy.pac"}); err != nil {
+		t.Fatalf("SetPac: %v", err)
+	}
+	if got := b.current(); got.Mode() != sysproxy.ModePAC || got.PAC.URL != "http://127.0.0.1/proxy.pac" {
+		t.Errorf("after SetPac = %+v", got)
+	}
+
+	if _, err := c.Disable(ctx, sysproxy.DisableParams{}); err != nil {
+		t.Fatalf("Disable: %v", err)
+	}
+	if got := b.current().Mode(); got != sysproxy.ModeDirect {
+		t.Errorf("after Disable mode = %s, want direct", got)
+	}
+}
+
+func TestSetProxyInvalid(t *testing.T) {
+	b := &fakeBackend{config: &sysproxy.ProxyConfig{}}
+	c := dial(t, b)
+
+	_, err := c.SetProxy(testContext(t), sysproxy.ProxyParams{Server: "no-port"})
+	if !errors.Is(err, sysproxy.ErrInvalidInput) {
+		t.Fatalf("SetProxy = %v, want ErrInvalidInput", err)
+	}
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadRequest || len(apiErr.Fields) == 0 {
+		t.Errorf("SetProxy error = %#v, want 400 with fields", err)
+	}
+	if got := b.current().Mode(); got != sysproxy.ModeDirect {
+		t.Errorf("invalid request changed mode to %s", got)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	b := &fakeBackend{config: sysproxy.NewPacConfig("http://127.0.0.1/proxy.pac")}
+	c := dial(t, b)
+	ctx := testContext(t)
+
+	events, err := c.Events(ctx, sysproxy.TargetParams{})
+	if err != nil {
+		t.Fatalf("Events: %v", err)
+	}
+	next := func() sysproxy.StateEvent {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatal("events closed")
+			}
+			return e
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for event")
+		}
+		return sysproxy.StateEvent{}
+	}
+
+	snapshot := next()
+	if snapshot.Source != "" || snapshot.Old != nil || !snapshot.New.Equal(b.current()) {
+		t.Errorf("snapshot event = %+v, want current settings only", snapshot)
+	}
+
+	if _, err := c.SetProxy(ctx, sysproxy.ProxyParams{Server: "127.0.0.1:7890"}); err != nil {
+		t.Fatalf("SetProxy: %v", err)
+	}
+	change := next()
+	if change.Source != sysproxy.EventSourceAPI || change.Old.Mode() != sysproxy.ModePAC || change.New.Mode() != sysproxy.ModeProxy {
+		t.Errorf("change event = %+v, want api pac -> proxy", change)
+	}
+}
+
+func TestLeaseHoldRelease(t *testing.T) {
+	initial := sysproxy.NewPacConfig("http://127.0.0.1/proxy.pac")
+	initial.Proxy.Servers = map[string]string{"http_server": "10.0.0.1:3128"}
+	initial.Proxy.Bypass = "localhost"
+	b := &fakeBackend{config: clone(initial)}
+	c := dial(t, b)
+	ctx := testContext(t)
+
+	l, err := c.Lease(ctx, sysproxy.LeaseParams{Owner: "test", Hold: true})
+	if err != nil {
+		t.Fatalf("Lease: %v", err)
+	}
+	if info := l.Info(); info.Owner != "test" || !info.Hold || !info.Snapshot.Equal(initial) {
+		t.Errorf("Lease info = %+v", info)
+	}
+	if err := l.Heartbeat(ctx); err != nil {
+		t.Errorf("Heartbeat: %v", err)
+	}
+
+	if _, err := c.SetProxy(ctx, sysproxy.ProxyParams{Server: "127.0.0.1:7890"}); err != nil {
+		t.Fatalf("SetProxy: %v", err)
+	}
+	if err := l.Release(ctx, true); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	select {
+	case <-l.Done():
+	case <-ctx.Done():
+		t.Fatal("hold connection not closed after Release")
+	}
+	if b.restores() != 1 || !b.current().Equal(initial) {
+		t.Errorf("after Release settings = %+v, restores = %d, want %+v", b.current(), b.restores(), initial)
+	}
+}
+
+func TestLeaseHoldClose(t *testing.T) {
+	b := &fakeBackend{config: &sysproxy.ProxyConfig{}}
+	c := dial(t, b)
+	ctx := testContext(t)
+
+	l, err := c.Lease(ctx, sysproxy.LeaseParams{Owner: "test", Hold: true})
+	if err != nil {
+		t.Fatalf("Lease: %v", err)
+	}
+	if _, err := c.SetProxy(ctx, sysproxy.ProxyParams{Server: "127.0.0.1:7890"}); err != nil {
+		t.Fatalf("SetProxy: %v", err)
+	}
+	l.Close()
+
+	// 服务在发现连接断开后异步恢复设置
+	for b.restores() == 0 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("settings not restored after the hold connection closed")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	if got := b.current().Mode(); got != sysproxy.ModeDirect {
+		t.Errorf("after Close mode = %s, want direct", got)
+	}
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/xishang0128/sysproxy-go/sysproxy"
+)
+
+// Events 订阅目标的代理设置变化，第一个事件为当前设置（Source 为空），
+// ctx 结束、服务停止或客户端处理过慢时关闭通道，需要时重新调用以继续订阅
+func (c *Client) Events(ctx context.Context, target sysproxy.TargetParams) (<-chan sysproxy.StateEvent, error) {
+	resp, err := c.send(ctx, http.MethodGet, "/events"+targetQuery(target), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan sysproxy.StateEvent)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(nil, 1<<20)
+		var data strings.Builder
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line != "" {
+				// 只关心 data 字段，event 名称可由 Source 区分，注释行为保活
+				if value, ok := strings.CutPrefix(line, "data:"); ok {
+					data.WriteString(strings.TrimPrefix(value, " "))
+				}
+				continue
+			}
+			if data.Len() == 0 {
+				continue
+			}
+			var event sysproxy.StateEvent
+			err := json.Unmarshal([]byte(data.String()), &event)
+			data.Reset()
+			if err != nil {
+				continue
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/xishang0128/sysproxy-go/i18n"
+	"github.com/xishang0128/sysproxy-go/sysproxy"
+)
+
+// Lease 为已创建的租约
+type Lease struct {
+	c    *Client
+	mu   sync.Mutex
+	info sysproxy.LeaseInfo
+	// body 为 Hold 模式下保持的连接，done 在连接断开时关闭
+	body io.Closer
+	done chan struct{}
+}
+
+// Lease 创建或续期租约，Hold 为 true 时保持连接直到 Release、Close 或 ctx 结束，
+// 连接断开后服务按 OnExpire 恢复设置
+func (c *Client) Lease(ctx context.Context, p sysproxy.LeaseParams) (*Lease, error) {
+	resp, err := c.send(ctx, http.MethodPost, "/lease", p)
+	if err != nil {
+		return nil, err
+	}
+	l := &Lease{c: c, done: make(chan struct{})}
+	if err := json.NewDecoder(resp.Body).Decode(&l.info); err != nil {
+		resp.Body.Close()
+		return nil, i18n.Errorf("解析响应失败：%w", err)
+	}
+	if !p.Hold {
+		resp.Body.Close()
+		return l, nil
+	}
+	l.body = resp.Body
+	go func() {
+		defer close(l.done)
+		_, _ = io.Copy(io.Discard, resp.Body)
+	}()
+	return l, nil
+}
+
+// Info 返回租约信息，Expires 在每次续期后更新
+func (l *Lease) Info() sysproxy.LeaseInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.info
+}
+
+// Done 在 Hold 模式的连接断开时关闭，其他模式下不会关闭
+func (l *Lease) Done() <-chan struct{} {
+	if l.body == nil {
+		return nil
+	}
+	return l.done
+}
+
+func (l *Lease) Heartbeat(ctx context.Context) error {
+	var info sysproxy.LeaseInfo
+	if err := l.c.do(ctx, http.MethodPost, "/lease/heartbeat", sysproxy.LeaseHeartbeat{Owner: l.Info().Owner}, &info); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.info = info
+	l.mu.Unlock()
+	return nil
+}
+
+// KeepAlive 每隔 TTL 的三分之一续期一次，直到 ctx 结束、续期失败或 Hold 模式的连接断开
+func (l *Lease) KeepAlive(ctx context.Context) error {
+	ttl, _ := time.ParseDuration(l.Info().TTL)
+	if ttl <= 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-l.Done():
+			return i18n.Errorf("租约连接已断开")
+		}
+	}
+
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-l.Done():
+			return i18n.Errorf("租约连接已断开")
+		case <-ticker.C:
+			if err := l.Heartbeat(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Release 释放租约，expire 为 true 时服务按 OnExpire 恢复设置
+func (l *Lease) Release(ctx context.Context, expire bool) error {
+	err := l.c.do(ctx, http.MethodDelete, "/lease", sysproxy.LeaseRelease{Owner: l.Info().Owner, Expire: expire}, nil)
+	l.Close()
+	return err
+}
+
+// Close 断开 Hold 模式的连接，租约随之过期，其他模式下不做任何操作
+func (l *Lease) Close() error {
+	if l.body == nil {
+		return nil
+	}
+	return l.body.Close()
+}
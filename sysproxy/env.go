@@ -0,0 +1,187 @@
+package sysproxy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type EnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+var ProxyEnvNames = []string{"http_proxy", "https_proxy", "all_proxy", "no_proxy"}
+
+// ProxyEnv 将代理设置转换为 http_proxy 等环境变量，小写与大写形式都会给出
+func ProxyEnv(config *ProxyConfig) []EnvVar {
+	servers := map[string]string{}
+	for key, value := range config.Proxy.Servers {
+		if strings.Contains(value, "=") {
+			for k, v := range parseServerList(value) {
+				servers[k] = v
+			}
+			continue
+		}
+		if value != "" {
+			servers[key] = value
+		}
+	}
+
+	values := map[string]string{}
+	if server := servers["http_server"]; server != "" {
+		values["http_proxy"] = withScheme(server, "http")
+	}
+	if server := servers["https_server"]; server != "" {
+		values["https_proxy"] = withScheme(server, "http")
+	}
+	switch {
+	case servers["socks_server"] != "":
+		values["all_proxy"] = withScheme(servers["socks_server"], "socks5")
+	case servers["http_server"] != "":
+		values["all_proxy"] = values["http_proxy"]
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	if noProxy := NoProxy(config.Proxy.Bypass); noProxy != "" {
+		values["no_proxy"] = noProxy
+	}
+
+	var env []EnvVar
+	for _, name := range ProxyEnvNames {
+		if value, ok := values[name]; ok {
+			env = append(env, EnvVar{name, value}, EnvVar{strings.ToUpper(name), value})
+		}
+	}
+	return env
+}
+
+// NoProxy 将系统的绕过列表转换为 NO_PROXY 语法
+func NoProxy(bypass string) string {
+	var items []string
+	add := func(values ...string) {
+		for _, v := range values {
+			if v != "" && !slices.Contains(items, v) {
+				items = append(items, v)
+			}
+		}
+	}
+
+	for _, item := range strings.FieldsFunc(bypass, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\n' || r == '\t'
+	}) {
+		item = cleanOutput(item)
+		switch {
+		case item == "":
+		case item == "<local>":
+			add("localhost", "127.0.0.1", "::1")
+		case item == "*":
+			add(item)
+		case strings.HasPrefix(item, "*."):
+			add(item[1:])
+		case isIPv4Wildcard(item):
+			add(wildcardToCIDR(item))
+		case strings.Contains(item, "/"):
+			add(expandCIDR(item))
+		case strings.HasPrefix(item, "*"):
+			add(strings.TrimPrefix(item, "*"))
+		case strings.Contains(item, "*"):
+			// NO_PROXY 不支持中间的通配符，这类条目无法转换
+		default:
+			add(item)
+		}
+	}
+	return strings.Join(items, ",")
+}
+
+func isIPv4Wildcard(item string) bool {
+	parts := strings.Split(item, ".")
+	if len(parts) > 4 || parts[len(parts)-1] != "*" {
+		return false
+	}
+	wildcard := false
+	for _, part := range parts {
+		switch {
+		case part == "*":
+			wildcard = true
+		case wildcard || !isOctet(part):
+			return false
+		}
+	}
+	return true
+}
+
+func wildcardToCIDR(item string) string {
+	octets := []string{"0", "0", "0", "0"}
+	bits := 0
+	for i, part := range strings.Split(item, ".") {
+		if part == "*" {
+			break
+		}
+		octets[i] = part
+		bits += 8
+	}
+	return fmt.Sprintf("%s/%d", strings.Join(octets, "."), bits)
+}
+
+// expandCIDR 补全 macOS 中 169.254/16 这类简写
+func expandCIDR(item string) string {
+	addr, bits, _ := strings.Cut(item, "/")
+	parts := strings.Split(addr, ".")
+	if strings.Contains(addr, ":") || len(parts) >= 4 {
+		return item
+	}
+	for _, part := range parts {
+		if !isOctet(part) {
+			return item
+		}
+	}
+	for len(parts) < 4 {
+		parts = append(parts, "0")
+	}
+	return strings.Join(parts, ".") + "/" + bits
+}
+
+func isOctet(s string) bool {
+	if s == "" || len(s) > 3 {
+		return false
+	}
+	n := 0
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n <= 255
+}
+
+func withScheme(server, scheme string) string {
+	if strings.Contains(server, "://") {
+		return server
+	}
+	return scheme + "://" + server
+}
+
+// parseServerList 解析 Windows 中 http=host:port;https=host:port 形式的代理地址
+func parseServerList(s string) map[string]string {
+	servers := map[string]string{}
+	for _, item := range strings.Split(s, ";") {
+		protocol, addr, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok || addr == "" {
+			continue
+		}
+		switch strings.ToLower(protocol) {
+		case "http":
+			servers["http_server"] = addr
+		case "https":
+			servers["https_server"] = addr
+		case "socks", "socks5":
+			servers["socks_server"] = addr
+		case "ftp":
+			servers["ftp_server"] = addr
+		}
+	}
+	return servers
+}
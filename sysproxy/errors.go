@@ -0,0 +1,117 @@
+package sysproxy
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/xishang0128/sysproxy-go/i18n"
+)
+
+type ErrorCode string
+
+const (
+	CodeUnsupported  ErrorCode = "unsupported"
+	CodeInvalidInput ErrorCode = "invalid_input"
+	CodeSystem       ErrorCode = "system"
+	CodeUnavailable  ErrorCode = "unavailable"
+	CodeForbidden    ErrorCode = "forbidden"
+	CodeUnauthorized ErrorCode = "unauthorized"
+	CodeConflict     ErrorCode = "conflict"
+)
+
+// Error 携带稳定的错误码，调用方可通过 errors.Is 与 ErrUnsupported 等比较，
+// 错误信息按 i18n 的当前语言翻译，不应用于判断错误类型
+type Error struct {
+	Code   ErrorCode
+	format string
+	args   []any
+}
+
+var (
+	ErrUnsupported  = &Error{Code: CodeUnsupported}
+	ErrInvalidInput = &Error{Code: CodeInvalidInput}
+	ErrSystem       = &Error{Code: CodeSystem}
+	ErrUnavailable  = &Error{Code: CodeUnavailable}
+	ErrForbidden    = &Error{Code: CodeForbidden}
+	ErrUnauthorized = &Error{Code: CodeUnauthorized}
+	ErrConflict     = &Error{Code: CodeConflict}
+)
+
+func newError(code ErrorCode, format string, args ...any) error {
+	return &Error{Code: code, format: format, args: args}
+}
+
+func (e *Error) Error() string {
+	if e.format == "" {
+		return string(e.Code)
+	}
+	return i18n.Sprintf(e.format, e.args...)
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.format == "" && t.Code == e.Code
+}
+
+func (e *Error) Unwrap() error {
+	for _, arg := range e.args {
+		if err, ok := arg.(error); ok {
+			return err
+		}
+	}
+	return nil
+}
+
+func CodeOf(err error) ErrorCode {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	var fields FieldErrors
+	if errors.As(err, &fields) {
+		return CodeInvalidInput
+	}
+	return ""
+}
+
+// resultOf 返回指标与审计日志中的结果，成功时为 ok，否则为错误码
+func resultOf(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	if code := CodeOf(err); code != "" {
+		return string(code)
+	}
+	return "error"
+}
+
+// FieldErrors 按字段名记录参数校验错误，与 ErrInvalidInput 匹配
+type FieldErrors map[string]string
+
+func (e FieldErrors) Error() string {
+	var parts []string
+	for _, field := range slices.Sorted(maps.Keys(e)) {
+		parts = append(parts, fmt.Sprintf("%s: %s", field, e[field]))
+	}
+	return i18n.Sprintf("参数无效：%s", strings.Join(parts, "; "))
+}
+
+func (e FieldErrors) Is(target error) bool {
+	return target == ErrInvalidInput
+}
+
+func (e FieldErrors) add(field, format string, args ...any) {
+	if _, ok := e[field]; !ok {
+		e[field] = i18n.Sprintf(format, args...)
+	}
+}
+
+func (e FieldErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,168 @@
+//go:build darwin || linux
+
+package sysproxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// eventKeepAlive 为没有事件时发送注释的间隔，用于及时发现断开的连接
+var eventKeepAlive = 30 * time.Second
+
+// stateHub 为每个目标共享一个 WatchDevice，并向订阅者推送设置变化
+type stateHub struct {
+	mu       sync.Mutex
+	watchers map[TargetParams]*stateWatcher
+}
+
+type stateWatcher struct {
+	target TargetParams
+	last   *ProxyConfig
+	subs   map[chan StateEvent]struct{}
+	cancel context.CancelFunc
+}
+
+var stateEvents = &stateHub{watchers: map[TargetParams]*stateWatcher{}}
+
+// subscribe 返回当前设置与后续的变化，变化积压过多时关闭通道，客户端应重新连接
+func (h *stateHub) subscribe(target TargetParams) (*ProxyConfig, <-chan StateEvent, func(), error) {
+	current, err := backend().Query(target.Device, target.OnlyActiveDevice)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	ch := make(chan StateEvent, 16)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	w := h.watchers[target]
+	if w == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		w = &stateWatcher{target: target, last: current, subs: map[chan StateEvent]struct{}{}, cancel: cancel}
+		h.watchers[target] = w
+		go h.watch(ctx, w)
+	}
+	w.subs[ch] = struct{}{}
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := w.subs[ch]; ok {
+			delete(w.subs, ch)
+			close(ch)
+		}
+		if len(w.subs) == 0 && h.watchers[target] == w {
+			w.cancel()
+			delete(h.watchers, target)
+		}
+	}
+	return current, ch, unsubscribe, nil
+}
+
+func (h *stateHub) watch(ctx context.Context, w *stateWatcher) {
+	for e := range watchDevice(ctx, backend(), WatchOptions{Device: w.target.Device, OnlyActiveDevice: w.target.OnlyActiveDevice}) {
+		h.publish(w, e.New, EventSourceSystem)
+	}
+}
+
+// refresh 在修改队列执行修改后重新查询各目标的设置，使订阅者立即收到变化，source 为事件的来源
+func (h *stateHub) refresh(source string) {
+	h.mu.Lock()
+	watchers := make([]*stateWatcher, 0, len(h.watchers))
+	for _, w := range h.watchers {
+		watchers = append(watchers, w)
+	}
+	h.mu.Unlock()
+
+	for _, w := range watchers {
+		current, err := backend().Query(w.target.Device, w.target.OnlyActiveDevice)
+		if err != nil {
+			logger().Warn("query proxy settings for events failed", "device", w.target.Device, "error", err)
+			continue
+		}
+		h.publish(w, current, source)
+	}
+}
+
+func (h *stateHub) publish(w *stateWatcher, config *ProxyConfig, source string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if w.last.Equal(config) {
+		return
+	}
+	event := StateEvent{Time: time.Now(), Source: source, Old: w.last, New: config}
+	w.last = config
+	for ch := range w.subs {
+		select {
+		case ch <- event:
+		default:
+			logger().Warn("event subscriber too slow, closing", "device", w.target.Device)
+			delete(w.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+func v1Events(w http.ResponseWriter, r *http.Request) {
+	var p TargetParams
+	if err := decodeParams(r, &p); err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		sendAPIError(w, newError(CodeUnsupported, "连接不支持流式响应"))
+		return
+	}
+
+	current, events, unsubscribe, err := stateEvents.subscribe(p)
+	if err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := writeEvent(w, "snapshot", StateEvent{Time: time.Now(), New: current}); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-serverStopping(r.Context()):
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, "change", event); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, name string, event StateEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+	return err
+}
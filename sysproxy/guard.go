@@ -0,0 +1,144 @@
+package sysproxy
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Correction struct {
+	Time  time.Time `json:"time"`
+	Diff  []string  `json:"diff"`
+	Error string    `json:"error,omitempty"`
+}
+
+type GuardStatus struct {
+	Desired     *ProxyConfig `json:"desired"`
+	Corrections int          `json:"corrections"`
+	Last        *Correction  `json:"last,omitempty"`
+}
+
+// Guard 保持期望的代理设置，在被其他程序修改后重新应用
+type Guard struct {
+	Desired          *ProxyConfig
+	Device           string
+	OnlyActiveDevice bool
+
+	// MinInterval 为两次纠正之间的最小间隔，连续纠正时按指数退避直到 MaxBackoff
+	MinInterval time.Duration
+	MaxBackoff  time.Duration
+	// WatchInterval 为没有变更通知时检查偏离的轮询间隔，为 0 时使用 DefaultWatchInterval
+	WatchInterval time.Duration
+
+	OnCorrect func(Correction)
+	// Backend 为 nil 时修改系统的设置，服务通过它把纠正提交到修改队列
+	Backend Backend
+
+	mu          sync.Mutex
+	corrections int
+	last        *Correction
+}
+
+func (g *Guard) Run(ctx context.Context) error {
+	if err := orSystem(g.Backend).Apply(g.Desired, g.Device, g.OnlyActiveDevice); err != nil {
+		return err
+	}
+	g.Hold(ctx)
+	return nil
+}
+
+// Hold 在不主动应用期望设置的情况下监视并纠正偏离，直到 ctx 结束
+func (g *Guard) Hold(ctx context.Context) {
+	minInterval := g.MinInterval
+	if minInterval <= 0 {
+		minInterval = 5 * time.Second
+	}
+	maxBackoff := g.MaxBackoff
+	if maxBackoff < minInterval {
+		maxBackoff = 5 * time.Minute
+	}
+
+	b := orSystem(g.Backend)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events := watchDevice(ctx, b, WatchOptions{Device: g.Device, OnlyActiveDevice: g.OnlyActiveDevice, Interval: g.WatchInterval})
+
+	var (
+		lastCorrection time.Time
+		delay          = minInterval
+		timer          *time.Timer
+		fire           <-chan time.Time
+	)
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if fire != nil || len(Drift(g.Desired, event.New)) == 0 {
+				continue
+			}
+			// 距上次纠正不足一个退避周期时延后处理，避免与其他程序反复争夺
+			wait := time.Until(lastCorrection.Add(delay))
+			if wait < 0 {
+				wait = 0
+			}
+			timer = time.NewTimer(wait)
+			fire = timer.C
+		case <-fire:
+			fire = nil
+			actual, err := b.Query(g.Device, g.OnlyActiveDevice)
+			if err != nil {
+				continue
+			}
+			diff := Drift(g.Desired, actual)
+			if len(diff) == 0 {
+				continue
+			}
+
+			if time.Since(lastCorrection) < delay*2 {
+				delay = min(delay*2, maxBackoff)
+			} else {
+				delay = minInterval
+			}
+			lastCorrection = time.Now()
+
+			correction := Correction{Time: lastCorrection, Diff: diff}
+			if err := b.Apply(g.Desired, g.Device, g.OnlyActiveDevice); err != nil {
+				correction.Error = err.Error()
+				timer = time.NewTimer(delay)
+				fire = timer.C
+			}
+			g.record(correction)
+		}
+	}
+}
+
+func (g *Guard) Status() GuardStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return GuardStatus{
+		Desired:     g.Desired,
+		Corrections: g.corrections,
+		Last:        g.last,
+	}
+}
+
+func (g *Guard) record(correction Correction) {
+	g.mu.Lock()
+	g.corrections++
+	g.last = &correction
+	g.mu.Unlock()
+
+	if g.OnCorrect != nil {
+		g.OnCorrect(correction)
+	}
+}
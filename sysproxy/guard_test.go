@@ -0,0 +1,166 @@
+package sysproxy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// holdGuard 在后台运行 g.Hold，返回纠正记录与 Hold 返回时关闭的通道
+func holdGuard(t *testing.T, g *Guard) (<-chan Correction, <-chan struct{}, context.CancelFunc) {
+	pollOnly(t)
+	corrections := make(chan Correction, 16)
+	g.WatchInterval = 5 * time.Millisecond
+	if g.MinInterval == 0 {
+		g.MinInterval = 10 * time.Millisecond
+	}
+	g.OnCorrect = func(c Correction) {
+		select {
+		case corrections <- c:
+		default:
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.Hold(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	// 等待 Hold 读取初始设置，之后的修改才会被视为变化
+	time.Sleep(20 * time.Millisecond)
+	return corrections, done, cancel
+}
+
+func TestGuardDrift(t *testing.T) {
+	desired := NewProxyConfig("127.0.0.1:7890", "localhost,127.0.0.1")
+	tests := []struct {
+		name    string
+		changed *ProxyConfig
+		correct bool
+	}{
+		{"other server", NewProxyConfig("10.0.0.1:3128", "localhost,127.0.0.1"), true},
+		{"direct", &ProxyConfig{}, true},
+		{"pac", NewPacConfig("http://127.0.0.1/proxy.pac"), true},
+		{"other bypass", NewProxyConfig("127.0.0.1:7890", "localhost"), true},
+		{"bypass separator", NewProxyConfig("127.0.0.1:7890", "localhost;127.0.0.1"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &memoryBackend{config: desired}
+			corrections, _, _ := holdGuard(t, &Guard{Desired: desired, Backend: b})
+			b.set(tt.changed)
+
+			select {
+			case c := <-corrections:
+				if !tt.correct {
+					t.Fatalf("unexpected correction %+v", c)
+				}
+				if c.Error != "" || len(c.Diff) == 0 {
+					t.Errorf("correction = %+v, want diff without error", c)
+				}
+				if !b.current().Equal(desired) {
+					t.Errorf("settings = %+v, want %+v", b.current(), desired)
+				}
+			case <-time.After(300 * time.Millisecond):
+				if tt.correct {
+					t.Fatal("drift not corrected")
+				}
+				if applies, _ := b.counts(); applies != 0 {
+					t.Errorf("applied %d times without drift", applies)
+				}
+			}
+		})
+	}
+}
+
+func TestGuardBackoff(t *testing.T) {
+	desired := NewProxyConfig("127.0.0.1:7890", "")
+	rival := NewProxyConfig("10.0.0.1:3128", "")
+	b := &memoryBackend{config: desired, rival: rival}
+	guard := &Guard{Desired: desired, Backend: b, MinInterval: 20 * time.Millisecond, MaxBackoff: 40 * time.Millisecond}
+	corrections, _, _ := holdGuard(t, guard)
+	b.set(rival)
+
+	var times []time.Time
+	for len(times) < 5 {
+		select {
+		case c := <-corrections:
+			times = append(times, c.Time)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("got %d corrections, want 5", len(times))
+		}
+	}
+	// 连续纠正的间隔按 MinInterval 翻倍，直到 MaxBackoff
+	want := []time.Duration{20 * time.Millisecond, 40 * time.Millisecond, 40 * time.Millisecond, 40 * time.Millisecond}
+	for i, least := range want {
+		gap := times[i+1].Sub(times[i])
+		if gap < least {
+			t.Errorf("gap %d = %v, want at least %v", i, gap, least)
+		}
+		if gap >= 4*guard.MaxBackoff {
+			t.Errorf("gap %d = %v, want capped near %v", i, gap, guard.MaxBackoff)
+		}
+	}
+	if status := guard.Status(); status.Corrections < 5 || status.Last == nil {
+		t.Errorf("status = %+v, want at least 5 corrections", status)
+	}
+}
+
+func TestGuardRetry(t *testing.T) {
+	desired := NewProxyConfig("127.0.0.1:7890", "")
+	b := &memoryBackend{config: desired}
+	corrections, _, _ := holdGuard(t, &Guard{Desired: desired, Backend: b})
+
+	b.setErr(errors.New("apply failed"))
+	b.set(&ProxyConfig{})
+	select {
+	case c := <-corrections:
+		if c.Error != "apply failed" {
+			t.Fatalf("correction = %+v, want apply error", c)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("drift not corrected")
+	}
+	// 失败后无需新的变化也会在退避后重试
+	b.setErr(nil)
+	select {
+	case c := <-corrections:
+		if c.Error != "" {
+			t.Errorf("retry = %+v, want success", c)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("failed correction not retried")
+	}
+	if !b.current().Equal(desired) {
+		t.Errorf("settings = %+v, want %+v", b.current(), desired)
+	}
+}
+
+func TestGuardStop(t *testing.T) {
+	desired := NewProxyConfig("127.0.0.1:7890", "")
+	b := &memoryBackend{config: desired}
+	corrections, done, cancel := holdGuard(t, &Guard{Desired: desired, Backend: b})
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Hold did not return after cancel")
+	}
+	b.set(&ProxyConfig{})
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case c := <-corrections:
+		t.Errorf("correction after stop: %+v", c)
+	default:
+	}
+	if applies, _ := b.counts(); applies != 0 {
+		t.Errorf("applied %d times after stop", applies)
+	}
+}
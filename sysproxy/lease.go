@@ -0,0 +1,283 @@
+//go:build darwin || linux
+
+package sysproxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+// lease 为客户端持有的租约，客户端崩溃或退出而未释放时，过期后恢复租约开始前的设置
+type lease struct {
+	info  LeaseInfo
+	ttl   time.Duration
+	timer *time.Timer
+	// done 在租约被释放、过期或被同一 owner 的新租约取代时关闭
+	done chan struct{}
+}
+
+var leaseState struct {
+	sync.Mutex
+	leases map[string]*lease
+}
+
+// acquireLease 创建或续期租约，应在修改队列中调用
+func acquireLease(p LeaseParams) (*lease, error) {
+	ttl, _ := time.ParseDuration(p.TTL)
+	if p.OnExpire == "" {
+		p.OnExpire = LeaseRestore
+	}
+
+	leaseState.Lock()
+	defer leaseState.Unlock()
+	if leaseState.leases == nil {
+		leaseState.leases = map[string]*lease{}
+	}
+
+	// 同一目标上已有租约时沿用其快照，避免恢复成其他租约设置的代理
+	var snapshot *ProxyConfig
+	if old := leaseState.leases[p.Owner]; old != nil && old.info.TargetParams == p.TargetParams {
+		snapshot = old.info.Snapshot
+	}
+	for _, l := range leaseState.leases {
+		if snapshot == nil && l.info.TargetParams == p.TargetParams {
+			snapshot = l.info.Snapshot
+		}
+	}
+	if snapshot == nil {
+		current, err := backend().Query(p.Device, p.OnlyActiveDevice)
+		if err != nil {
+			return nil, err
+		}
+		snapshot = current
+	}
+
+	if old := leaseState.leases[p.Owner]; old != nil {
+		old.end()
+	}
+	l := &lease{
+		info: LeaseInfo{
+			Owner:        p.Owner,
+			TTL:          p.TTL,
+			OnExpire:     p.OnExpire,
+			Hold:         p.Hold,
+			Snapshot:     snapshot,
+			TargetParams: p.TargetParams,
+		},
+		ttl:  ttl,
+		done: make(chan struct{}),
+	}
+	if ttl > 0 {
+		l.timer = time.AfterFunc(ttl, func() {
+			expireLease(l, "ttl expired")
+		})
+		l.touch()
+	}
+	leaseState.leases[p.Owner] = l
+	logger().Info("lease acquired", "owner", p.Owner, "ttl", p.TTL, "hold", p.Hold, "on_expire", p.OnExpire)
+	return l, nil
+}
+
+func heartbeatLease(owner string) (LeaseInfo, error) {
+	leaseState.Lock()
+	defer leaseState.Unlock()
+	l := leaseState.leases[owner]
+	if l == nil {
+		return LeaseInfo{}, newError(codeNotFound, "未找到租约：%s", owner)
+	}
+	if l.timer != nil {
+		l.timer.Reset(l.ttl)
+		l.touch()
+	}
+	return l.info, nil
+}
+
+func releaseLease(owner string, expire bool) error {
+	leaseState.Lock()
+	l := leaseState.leases[owner]
+	leaseState.Unlock()
+	if l == nil {
+		return newError(codeNotFound, "未找到租约：%s", owner)
+	}
+	if expire {
+		expireLease(l, "released")
+		return nil
+	}
+	if removed, _ := removeLease(l); removed {
+		logger().Info("lease released", "owner", owner)
+	}
+	return nil
+}
+
+// expireLease 移除租约，同一目标上没有其他租约时按 OnExpire 恢复设置
+func expireLease(l *lease, reason string) {
+	removed, shared := removeLease(l)
+	if !removed {
+		return
+	}
+	if shared {
+		logger().Warn("lease expired, other leases still active", "owner", l.info.Owner, "reason", reason)
+		return
+	}
+
+	config := l.info.Snapshot
+	var err error
+	if l.info.OnExpire == LeaseDisable {
+		config = &ProxyConfig{}
+		_, err = applyQueued(context.Background(), "lease_expire", config, l.info.TargetParams, true)
+	} else {
+		err = restoreQueued(context.Background(), "lease_expire", config, l.info.TargetParams)
+	}
+	if err != nil {
+		logger().Error("lease expired, restore failed", "owner", l.info.Owner, "reason", reason, "error", err)
+		return
+	}
+	logger().Warn("lease expired, proxy settings restored", "owner", l.info.Owner, "reason", reason, "mode", config.Mode())
+}
+
+// removeLease 在 l 仍为当前租约时移除它，并报告同一目标上是否还有其他租约
+func removeLease(l *lease) (removed, shared bool) {
+	leaseState.Lock()
+	defer leaseState.Unlock()
+	if leaseState.leases[l.info.Owner] != l {
+		return false, false
+	}
+	delete(leaseState.leases, l.info.Owner)
+	l.end()
+	for _, o := range leaseState.leases {
+		shared = shared || o.info.TargetParams == l.info.TargetParams
+	}
+	return true, shared
+}
+
+// touch 更新过期时间，调用时需持有 leaseState 的锁
+func (l *lease) touch() {
+	expires := time.Now().Add(l.ttl)
+	l.info.Expires = &expires
+}
+
+func (l *lease) end() {
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+	close(l.done)
+}
+
+// dropLeases 在服务停止时丢弃所有租约，不恢复设置
+func dropLeases() {
+	leaseState.Lock()
+	defer leaseState.Unlock()
+	for owner, l := range leaseState.leases {
+		delete(leaseState.leases, owner)
+		l.end()
+	}
+}
+
+func leasesActive() bool {
+	leaseState.Lock()
+	defer leaseState.Unlock()
+	return len(leaseState.leases) > 0
+}
+
+func listLeases() []LeaseInfo {
+	leaseState.Lock()
+	defer leaseState.Unlock()
+	infos := []LeaseInfo{}
+	for _, l := range leaseState.leases {
+		infos = append(infos, l.info)
+	}
+	slices.SortFunc(infos, func(a, b LeaseInfo) int {
+		return strings.Compare(a.Owner, b.Owner)
+	})
+	return infos
+}
+
+func v1Leases(w http.ResponseWriter, r *http.Request) {
+	var p struct{}
+	if err := decodeParams(r, &p); err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	render.JSON(w, r, listLeases())
+}
+
+func v1AcquireLease(w http.ResponseWriter, r *http.Request) {
+	var p LeaseParams
+	if err := decodeParams(r, &p); err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	// 在修改队列中取得快照，避免取到尚未执行完的修改之前或之间的设置
+	var l *lease
+	err := mutate(r.Context(), "lease_acquire", p.TargetParams, func() error {
+		var err error
+		l, err = acquireLease(p)
+		return err
+	})
+	if err != nil {
+		sendAPIError(w, err)
+		return
+	}
+
+	leaseState.Lock()
+	info := l.info
+	leaseState.Unlock()
+	if !p.Hold {
+		render.JSON(w, r, info)
+		return
+	}
+
+	// 保持连接：先返回租约信息，连接断开时租约立即过期
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		removeLease(l)
+		sendAPIError(w, newError(CodeUnsupported, "连接不支持流式响应"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(info)
+	flusher.Flush()
+
+	select {
+	case <-r.Context().Done():
+		expireLease(l, "connection closed")
+	case <-serverStopping(r.Context()):
+		// 服务停止时断开连接，租约随服务一同结束，不恢复设置
+	case <-l.done:
+	}
+}
+
+func v1HeartbeatLease(w http.ResponseWriter, r *http.Request) {
+	var p LeaseHeartbeat
+	if err := decodeParams(r, &p); err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	info, err := heartbeatLease(p.Owner)
+	if err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	render.JSON(w, r, info)
+}
+
+func v1ReleaseLease(w http.ResponseWriter, r *http.Request) {
+	var p LeaseRelease
+	if err := decodeParams(r, &p); err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	if err := releaseLease(p.Owner, p.Expire); err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	render.NoContent(w, r)
+}
@@ -0,0 +1,31 @@
+package sysproxy
+
+import (
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+var customLogger atomic.Pointer[slog.Logger]
+
+// SetLogger 设置包内使用的日志记录器，为 nil 时使用 slog.Default()
+func SetLogger(l *slog.Logger) {
+	customLogger.Store(l)
+}
+
+func logger() *slog.Logger {
+	if l := customLogger.Load(); l != nil {
+		return l
+	}
+	return slog.Default()
+}
+
+// logCall 记录一次操作及其耗时，失败时使用 Error 级别
+func logCall(msg string, start time.Time, err error, args ...any) {
+	args = append(args, "took", time.Since(start))
+	if err != nil {
+		logger().Error(msg, append(args, "error", err)...)
+		return
+	}
+	logger().Info(msg, args...)
+}
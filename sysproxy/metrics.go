@@ -0,0 +1,172 @@
+//go:build darwin || linux
+
+package sysproxy
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyBuckets 为耗时直方图各桶的上界，单位为秒
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type histogram struct {
+	// counts 为落入各桶的次数，最后一项为超过所有上界的次数
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(latencyBuckets)+1)
+	}
+	i, _ := slices.BinarySearch(latencyBuckets, v)
+	h.counts[i]++
+	h.sum += v
+	h.count++
+}
+
+type operationKey struct {
+	operation string
+	result    string
+}
+
+// serverMetrics 记录服务执行的操作，即使未开启 /metrics 也会记录
+var serverMetrics struct {
+	sync.Mutex
+	operations       map[operationKey]uint64
+	apply            histogram
+	query            histogram
+	guardCorrections map[string]uint64
+}
+
+// observeApply 记录一次应用设置的结果与耗时
+func observeApply(operation string, start time.Time, err error) {
+	observe(operation, &serverMetrics.apply, start, err)
+}
+
+// observeQuery 记录一次查询设置的结果与耗时
+func observeQuery(operation string, start time.Time, err error) {
+	observe(operation, &serverMetrics.query, start, err)
+}
+
+func observe(operation string, h *histogram, start time.Time, err error) {
+	countOperation(operation, err)
+	serverMetrics.Lock()
+	defer serverMetrics.Unlock()
+	h.observe(time.Since(start).Seconds())
+}
+
+// countOperation 只记录操作的结果，用于未执行到系统调用就失败的操作
+func countOperation(operation string, err error) {
+	serverMetrics.Lock()
+	defer serverMetrics.Unlock()
+	if serverMetrics.operations == nil {
+		serverMetrics.operations = map[operationKey]uint64{}
+	}
+	serverMetrics.operations[operationKey{operation, resultOf(err)}]++
+}
+
+func observeGuardCorrection(err string) {
+	result := "ok"
+	if err != "" {
+		result = "error"
+	}
+	serverMetrics.Lock()
+	defer serverMetrics.Unlock()
+	if serverMetrics.guardCorrections == nil {
+		serverMetrics.guardCorrections = map[string]uint64{}
+	}
+	serverMetrics.guardCorrections[result]++
+}
+
+func metrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetrics(w)
+}
+
+// writeMetrics 以 Prometheus 文本格式输出指标
+func writeMetrics(w io.Writer) {
+	m := &metricWriter{w: w}
+
+	m.header("sysproxy_build_info", "gauge", "Build information of the sysproxy helper.")
+	m.sample("sysproxy_build_info", 1, "version", Version, "api", strconv.Itoa(APIVersion), "goversion", runtime.Version())
+
+	serverMetrics.Lock()
+	m.header("sysproxy_operations_total", "counter", "Proxy setting operations by operation and result.")
+	keys := slices.SortedFunc(maps.Keys(serverMetrics.operations), func(a, b operationKey) int {
+		return cmp.Or(strings.Compare(a.operation, b.operation), strings.Compare(a.result, b.result))
+	})
+	for _, k := range keys {
+		m.sample("sysproxy_operations_total", float64(serverMetrics.operations[k]), "operation", k.operation, "result", k.result)
+	}
+	m.histogram("sysproxy_apply_duration_seconds", "Time taken to apply proxy settings.", &serverMetrics.apply)
+	m.histogram("sysproxy_query_duration_seconds", "Time taken to query proxy settings.", &serverMetrics.query)
+	m.header("sysproxy_guard_corrections_total", "counter", "Proxy settings restored by the guard after being changed by other programs.")
+	for _, result := range []string{"ok", "error"} {
+		m.sample("sysproxy_guard_corrections_total", float64(serverMetrics.guardCorrections[result]), "result", result)
+	}
+	serverMetrics.Unlock()
+
+	// 没有看门狗运行时所有状态均为 0
+	info := currentWatchdog()
+	m.header("sysproxy_watchdog_state", "gauge", "Current state of the watchdog, 1 for the active state.")
+	for _, state := range []WatchdogState{WatchdogHealthy, WatchdogFailing, WatchdogTripped} {
+		value := 0.0
+		if info.Watchdog != nil && info.Watchdog.State == state {
+			value = 1
+		}
+		m.sample("sysproxy_watchdog_state", value, "state", string(state))
+	}
+}
+
+type metricWriter struct {
+	w io.Writer
+}
+
+func (m *metricWriter) header(name, typ, help string) {
+	fmt.Fprintf(m.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sample 输出一个样本，labels 为交替的标签名与值
+func (m *metricWriter) sample(name string, value float64, labels ...string) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i := 0; i < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, "%s=\"%s\"", labels[i], labelEscaper.Replace(labels[i+1]))
+		}
+		b.WriteByte('}')
+	}
+	fmt.Fprintf(m.w, "%s %s\n", b.String(), strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+func (m *metricWriter) histogram(name, help string, h *histogram) {
+	m.header(name, "histogram", help)
+	var cumulative uint64
+	for i, le := range latencyBuckets {
+		if h.counts != nil {
+			cumulative += h.counts[i]
+		}
+		m.sample(name+"_bucket", float64(cumulative), "le", strconv.FormatFloat(le, 'g', -1, 64))
+	}
+	m.sample(name+"_bucket", float64(h.count), "le", "+Inf")
+	m.sample(name+"_sum", h.sum)
+	m.sample(name+"_count", float64(h.count))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
@@ -0,0 +1,81 @@
+//go:build darwin || linux
+
+package sysproxy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMetricWriterHistogram(t *testing.T) {
+	var h histogram
+	// 等于上界的值计入该桶，超过所有上界的值只计入 +Inf
+	for _, v := range []float64{0.25, 0.25, 0.75, 12} {
+		h.observe(v)
+	}
+	var b strings.Builder
+	m := &metricWriter{w: &b}
+	m.histogram("test_duration_seconds", "Test durations.", &h)
+	m.histogram("test_empty_seconds", "Empty histogram.", &histogram{})
+
+	want := `# HELP test_duration_seconds Test durations.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{le="0.005"} 0
+test_duration_seconds_bucket{le="0.01"} 0
+test_duration_seconds_bucket{le="0.025"} 0
+test_duration_seconds_bucket{le="0.05"} 0
+test_duration_seconds_bucket{le="0.1"} 0
+test_duration_seconds_bucket{le="0.25"} 2
+test_duration_seconds_bucket{le="0.5"} 2
+test_duration_seconds_bucket{le="1"} 3
+test_duration_seconds_bucket{le="2.5"} 3
+test_duration_seconds_bucket{le="5"} 3
+test_duration_seconds_bucket{le="10"} 3
+test_duration_seconds_bucket{le="+Inf"} 4
+test_duration_seconds_sum 13.25
+test_duration_seconds_count 4
+# HELP test_empty_seconds Empty histogram.
+# TYPE test_empty_seconds histogram
+test_empty_seconds_bucket{le="0.005"} 0
+test_empty_seconds_bucket{le="0.01"} 0
+test_empty_seconds_bucket{le="0.025"} 0
+test_empty_seconds_bucket{le="0.05"} 0
+test_empty_seconds_bucket{le="0.1"} 0
+test_empty_seconds_bucket{le="0.25"} 0
+test_empty_seconds_bucket{le="0.5"} 0
+test_empty_seconds_bucket{le="1"} 0
+test_empty_seconds_bucket{le="2.5"} 0
+test_empty_seconds_bucket{le="5"} 0
+test_empty_seconds_bucket{le="10"} 0
+test_empty_seconds_bucket{le="+Inf"} 0
+test_empty_seconds_sum 0
+test_empty_seconds_count 0
+`
+	if got := b.String(); got != want {
+		t.Errorf("histogram output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMetricWriterSample(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  float64
+		labels []string
+		want   string
+	}{
+		{"no labels", 1, nil, "test_total 1\n"},
+		{"labels", 3, []string{"operation", "proxy", "result", "ok"}, `test_total{operation="proxy",result="ok"} 3` + "\n"},
+		{"escaped", 1, []string{"operation", `a\b"c` + "\nd"}, `test_total{operation="a\\b\"c\nd"} 1` + "\n"},
+		{"utf-8", 1, []string{"version", "测试"}, `test_total{version="测试"} 1` + "\n"},
+		{"fraction", 0.5, nil, "test_total 0.5\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			(&metricWriter{w: &b}).sample("test_total", tt.value, tt.labels...)
+			if got := b.String(); got != tt.want {
+				t.Errorf("sample = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+//go:build darwin || linux
+
+package sysproxy
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// mutation 为一次修改设置的请求，所有修改由 mutations 依次执行，避免外部命令交错
+type mutation struct {
+	// operation 为审计日志中记录的操作名
+	operation string
+	// config 非 nil 时应用该设置，队列中同一目标尚未执行的设置会被后提交的设置取代
+	config *ProxyConfig
+	target TargetParams
+	force  bool
+	// restore 为 true 时通过 Restore 写入完整的快照，只与同为恢复的设置合并
+	restore bool
+	// run 非 nil 时执行其他修改，如启动保持，不参与合并
+	run func() error
+	// background 为 true 时为保持或看门狗在后台进行的修改，事件的来源记为 system
+	background bool
+	waiters    []chan mutationResult
+	callers    []AuditCaller
+}
+
+type mutationResult struct {
+	config *ProxyConfig
+	err    error
+}
+
+type mutationQueue struct {
+	mu      sync.Mutex
+	pending []*mutation
+	once    sync.Once
+	wake    chan struct{}
+}
+
+var mutations mutationQueue
+
+// applyQueued 通过队列应用设置，请求被合并时返回最终应用的设置
+func applyQueued(ctx context.Context, operation string, config *ProxyConfig, target TargetParams, force bool) (*ProxyConfig, error) {
+	result := mutations.submit(ctx, &mutation{operation: operation, config: config, target: target, force: force})
+	return result.config, result.err
+}
+
+// restoreQueued 通过队列恢复 QueryProxySettings 保存的快照，总是先停止保持与看门狗
+func restoreQueued(ctx context.Context, operation string, snapshot *ProxyConfig, target TargetParams) error {
+	return mutations.submit(ctx, &mutation{operation: operation, config: snapshot, target: target, force: true, restore: true}).err
+}
+
+// mutate 通过队列执行其他修改，target 为审计日志中记录前后设置的目标
+func mutate(ctx context.Context, operation string, target TargetParams, run func() error) error {
+	return mutations.submit(ctx, &mutation{operation: operation, target: target, run: run}).err
+}
+
+// queuedBackend 把保持与看门狗在后台的修改提交到修改队列，与接口的修改依次执行，
+// ctx 结束后尚未执行的修改会被跳过，以免在保持或看门狗停止后覆盖新的设置
+type queuedBackend struct {
+	ctx context.Context
+	// apply 与 restore 为审计日志与指标中记录的操作名
+	apply   string
+	restore string
+	target  TargetParams
+}
+
+func (b queuedBackend) Query(device string, onlyActiveDevice bool) (*ProxyConfig, error) {
+	return backend().Query(device, onlyActiveDevice)
+}
+
+func (b queuedBackend) Apply(config *ProxyConfig, device string, onlyActiveDevice bool) error {
+	return b.submit(b.apply, func() error {
+		return backend().Apply(config, device, onlyActiveDevice)
+	})
+}
+
+func (b queuedBackend) Restore(config *ProxyConfig, device string, onlyActiveDevice bool) error {
+	return b.submit(b.restore, func() error {
+		return backend().Restore(config, device, onlyActiveDevice)
+	})
+}
+
+func (b queuedBackend) submit(operation string, change func() error) error {
+	return mutations.submit(b.ctx, &mutation{operation: operation, target: b.target, background: true, run: func() error {
+		if err := b.ctx.Err(); err != nil {
+			return err
+		}
+		t := time.Now()
+		err := change()
+		logCall(operation, t, err, "device", b.target.Device)
+		observeApply(operation, t, err)
+		return err
+	}}).err
+}
+
+func (q *mutationQueue) submit(ctx context.Context, m *mutation) mutationResult {
+	q.once.Do(func() {
+		q.wake = make(chan struct{}, 1)
+		go q.loop()
+	})
+
+	done := make(chan mutationResult, 1)
+	caller := auditCaller(ctx)
+	q.mu.Lock()
+	if p := q.supersedes(m); p != nil {
+		logger().Debug("mutation coalesced", "mode", m.config.Mode(), "device", m.target.Device)
+		p.operation = m.operation
+		p.config = m.config
+		p.waiters = append(p.waiters, done)
+		m = p
+	} else {
+		m.waiters = []chan mutationResult{done}
+		q.pending = append(q.pending, m)
+	}
+	if caller != nil {
+		m.callers = append(m.callers, *caller)
+	}
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+
+	select {
+	case result := <-done:
+		return result
+	case <-ctx.Done():
+		return mutationResult{err: ctx.Err()}
+	}
+}
+
+// supersedes 返回可被 m 取代的待执行设置，只与队尾的设置合并，
+// 以免越过其他目标的设置或其他修改而改变执行顺序
+func (q *mutationQueue) supersedes(m *mutation) *mutation {
+	if m.config == nil || len(q.pending) == 0 {
+		return nil
+	}
+	p := q.pending[len(q.pending)-1]
+	if p.run == nil && p.target == m.target && p.force == m.force && p.restore == m.restore {
+		return p
+	}
+	return nil
+}
+
+func (q *mutationQueue) loop() {
+	for range q.wake {
+		for {
+			q.mu.Lock()
+			if len(q.pending) == 0 {
+				q.mu.Unlock()
+				break
+			}
+			m := q.pending[0]
+			q.pending = q.pending[1:]
+			q.mu.Unlock()
+
+			result := m.exec()
+			for _, done := range m.waiters {
+				done <- result
+			}
+			source := EventSourceAPI
+			if m.background {
+				source = EventSourceSystem
+			}
+			stateEvents.refresh(source)
+		}
+	}
+}
+
+// exec 执行修改，开启审计日志时记录修改前后的设置
+func (m *mutation) exec() mutationResult {
+	var before *ProxyConfig
+	if auditing() {
+		before, _ = backend().Query(m.target.Device, m.target.OnlyActiveDevice)
+	}
+	t := time.Now()
+	result := m.do()
+	m.audit(before, t, result.err)
+	return result
+}
+
+func (m *mutation) do() mutationResult {
+	if m.run != nil {
+		return mutationResult{err: m.run()}
+	}
+	if err := takeOver(m.force); err != nil {
+		countOperation(m.operation, err)
+		return mutationResult{err: err}
+	}
+	t := time.Now()
+	b := backend()
+	apply, msg := b.Apply, "apply proxy config"
+	if m.restore {
+		apply, msg = b.Restore, "restore proxy config"
+	}
+	err := apply(m.config, m.target.Device, m.target.OnlyActiveDevice)
+	logCall(msg, t, err, "mode", m.config.Mode(), "device", m.target.Device, "requests", len(m.waiters))
+	observeApply(m.operation, t, err)
+	return mutationResult{config: m.config, err: err}
+}
+
+func (m *mutation) audit(before *ProxyConfig, start time.Time, err error) {
+	if !auditing() {
+		return
+	}
+	record := AuditRecord{
+		Time:      start,
+		Source:    AuditSourceServer,
+		Operation: m.operation,
+		Device:    m.target.Device,
+		Callers:   m.callers,
+		Before:    before,
+		Duration:  time.Since(start),
+		Result:    resultOf(err),
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	record.After, _ = backend().Query(m.target.Device, m.target.OnlyActiveDevice)
+	writeAudit(record)
+}
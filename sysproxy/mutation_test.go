@@ -0,0 +1,169 @@
+//go:build darwin || linux
+
+package sysproxy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// logBackend 按执行顺序记录修改
+type logBackend struct {
+	mu  sync.Mutex
+	log []string
+}
+
+func (b *logBackend) Query(_ string, _ bool) (*ProxyConfig, error) {
+	return &ProxyConfig{}, nil
+}
+
+func (b *logBackend) Apply(config *ProxyConfig, device string, _ bool) error {
+	b.add(fmt.Sprintf("apply %s %s", device, config.Server()))
+	return nil
+}
+
+func (b *logBackend) Restore(config *ProxyConfig, device string, _ bool) error {
+	b.add(fmt.Sprintf("restore %s %s", device, config.Server()))
+	return nil
+}
+
+func (b *logBackend) add(entry string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.log = append(b.log, entry)
+}
+
+// useBackend 使服务在测试期间使用 b
+func useBackend(t *testing.T, b Backend) {
+	serverBackend.Lock()
+	serverBackend.backend = b
+	serverBackend.Unlock()
+	t.Cleanup(func() {
+		serverBackend.Lock()
+		serverBackend.backend = nil
+		serverBackend.Unlock()
+	})
+}
+
+// waitQueued 等待队列中有 pending 个待执行的修改，且队尾的修改有 waiters 个请求
+func waitQueued(t *testing.T, pending, waiters int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		mutations.mu.Lock()
+		n := len(mutations.pending)
+		ok := n == pending && len(mutations.pending[n-1].waiters) == waiters
+		mutations.mu.Unlock()
+		if ok {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("queue did not reach %d pending with %d waiters on the last", pending, waiters)
+}
+
+func TestMutationCoalescing(t *testing.T) {
+	b := &logBackend{}
+	useBackend(t, b)
+	ctx := context.Background()
+
+	// 阻塞队列，使之后的修改在队列中等待
+	block := make(chan struct{})
+	started := make(chan struct{})
+	go mutate(ctx, "block", TargetParams{}, func() error {
+		close(started)
+		<-block
+		return nil
+	})
+	<-started
+
+	type submitted struct {
+		name   string
+		result chan *ProxyConfig
+	}
+	var results []submitted
+	apply := func(name, server, device string, force bool) {
+		result := make(chan *ProxyConfig, 1)
+		results = append(results, submitted{name, result})
+		go func() {
+			config, err := applyQueued(ctx, "proxy", NewProxyConfig(server, ""), TargetParams{Device: device}, force)
+			if err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+			result <- config
+		}()
+	}
+	restore := func(server string) {
+		go func() {
+			if err := restoreQueued(ctx, "restore_on_exit", NewProxyConfig(server, ""), TargetParams{}); err != nil {
+				t.Errorf("restore %s: %v", server, err)
+			}
+		}()
+	}
+
+	steps := []struct {
+		submit           func()
+		pending, waiters int
+	}{
+		{func() { apply("a", "127.0.0.1:1", "", false) }, 1, 1},
+		// 与队尾同一目标的设置合并
+		{func() { apply("b", "127.0.0.1:2", "", false) }, 1, 2},
+		{func() { apply("c", "127.0.0.1:3", "en0", false) }, 2, 1},
+		// 队尾为其他目标时不越过它合并到 a
+		{func() { apply("d", "127.0.0.1:4", "", false) }, 3, 1},
+		{func() { apply("e", "127.0.0.1:5", "", true) }, 4, 1},
+		// 恢复只与恢复合并
+		{func() { restore("127.0.0.1:6") }, 5, 1},
+		{func() { restore("127.0.0.1:7") }, 5, 2},
+		{func() {
+			go mutate(ctx, "guard_start", TargetParams{}, func() error {
+				b.add("run")
+				return nil
+			})
+		}, 6, 1},
+		// 其他修改之后的设置不与之前的设置合并
+		{func() { apply("f", "127.0.0.1:8", "", false) }, 7, 1},
+		{func() { apply("g", "127.0.0.1:9", "", false) }, 7, 2},
+	}
+	for _, step := range steps {
+		step.submit()
+		waitQueued(t, step.pending, step.waiters)
+	}
+	close(block)
+
+	want := map[string]string{
+		"a": "127.0.0.1:2", "b": "127.0.0.1:2",
+		"c": "127.0.0.1:3",
+		"d": "127.0.0.1:4",
+		"e": "127.0.0.1:5",
+		"f": "127.0.0.1:9", "g": "127.0.0.1:9",
+	}
+	for _, r := range results {
+		select {
+		case config := <-r.result:
+			if config.Server() != want[r.name] {
+				t.Errorf("%s applied %s, want %s", r.name, config.Server(), want[r.name])
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s did not complete", r.name)
+		}
+	}
+
+	wantLog := []string{
+		"apply  127.0.0.1:2",
+		"apply en0 127.0.0.1:3",
+		"apply  127.0.0.1:4",
+		"apply  127.0.0.1:5",
+		"restore  127.0.0.1:7",
+		"run",
+		"apply  127.0.0.1:9",
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if fmt.Sprint(b.log) != fmt.Sprint(wantLog) {
+		t.Errorf("executed %q, want %q", b.log, wantLog)
+	}
+}
@@ -0,0 +1,248 @@
+package sysproxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Interface struct {
+	Name  string         `json:"name"`
+	Addrs []netip.Prefix `json:"addrs"`
+}
+
+type NetworkInfo struct {
+	Interfaces    []Interface  `json:"interfaces"`
+	Gateways      []netip.Addr `json:"gateways"`
+	SearchDomains []string     `json:"search_domains"`
+}
+
+// Rule 中指定的条件需全部满足才会匹配
+type Rule struct {
+	Gateway   string `json:"gateway,omitempty"`
+	Subnet    string `json:"subnet,omitempty"`
+	Interface string `json:"interface,omitempty"`
+	DNSSearch string `json:"dns_search,omitempty"`
+	Profile   string `json:"profile"`
+}
+
+func (r *Rule) Validate() error {
+	if r.Profile == "" {
+		return newError(CodeInvalidInput, "规则需要指定 profile")
+	}
+	if r.Gateway != "" {
+		if _, err := netip.ParseAddr(r.Gateway); err != nil {
+			return newError(CodeInvalidInput, "无效的网关地址：%s", r.Gateway)
+		}
+	}
+	if r.Subnet != "" {
+		if _, err := netip.ParsePrefix(r.Subnet); err != nil {
+			return newError(CodeInvalidInput, "无效的子网：%s", r.Subnet)
+		}
+	}
+	return nil
+}
+
+func (r *Rule) Match(info *NetworkInfo) bool {
+	if r.Gateway != "" {
+		gateway, err := netip.ParseAddr(r.Gateway)
+		if err != nil || !slices.Contains(info.Gateways, gateway) {
+			return false
+		}
+	}
+
+	if r.Interface != "" && !slices.ContainsFunc(info.Interfaces, func(i Interface) bool {
+		return i.Name == r.Interface
+	}) {
+		return false
+	}
+
+	if r.Subnet != "" {
+		subnet, err := netip.ParsePrefix(r.Subnet)
+		if err != nil {
+			return false
+		}
+		if !slices.ContainsFunc(info.Interfaces, func(i Interface) bool {
+			if r.Interface != "" && i.Name != r.Interface {
+				return false
+			}
+			return slices.ContainsFunc(i.Addrs, func(p netip.Prefix) bool {
+				return subnet.Contains(p.Addr())
+			})
+		}) {
+			return false
+		}
+	}
+
+	if r.DNSSearch != "" && !slices.ContainsFunc(info.SearchDomains, func(d string) bool {
+		return strings.EqualFold(strings.TrimSuffix(d, "."), strings.TrimSuffix(r.DNSSearch, "."))
+	}) {
+		return false
+	}
+	return true
+}
+
+func MatchRule(rules []Rule, info *NetworkInfo) *Rule {
+	for i := range rules {
+		if rules[i].Match(info) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+func QueryNetwork() (*NetworkInfo, error) {
+	ifaces, err := activeInterfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &NetworkInfo{}
+	for _, iface := range ifaces {
+		i := Interface{Name: iface.Name}
+		addrs, _ := iface.Addrs()
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				ip, _ := netip.AddrFromSlice(ipNet.IP)
+				ones, _ := ipNet.Mask.Size()
+				i.Addrs = append(i.Addrs, netip.PrefixFrom(ip.Unmap(), ones))
+			}
+		}
+		info.Interfaces = append(info.Interfaces, i)
+	}
+
+	info.Gateways, _ = defaultGateways()
+	info.SearchDomains = searchDomains()
+	return info, nil
+}
+
+// activeInterfaces 返回已启用、正在运行且有地址的网络接口
+func activeInterfaces() ([]net.Interface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, newError(CodeSystem, "无法获取网络接口：%v", err)
+	}
+
+	var active []net.Interface
+	for _, i := range ifaces {
+		if i.Flags&net.FlagUp == 0 || i.Flags&net.FlagRunning == 0 || i.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, _ := i.Addrs()
+		if len(addrs) == 0 {
+			continue
+		}
+		active = append(active, i)
+	}
+	return active, nil
+}
+
+func parseResolvConf(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var domains []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "search":
+			domains = fields[1:]
+		case "domain":
+			domains = fields[1:2]
+		}
+	}
+	return domains
+}
+
+// AutoSwitch 在网络变化时按规则应用匹配的代理配置，Linux 通过 netlink、macOS 通过路由套接字接收
+// 网络变化，无法订阅时每隔 Interval 轮询
+type AutoSwitch struct {
+	Profiles         *ProfileFile
+	Device           string
+	OnlyActiveDevice bool
+	// Interval 为轮询间隔，订阅网络变化时为应用失败后重试的间隔
+	Interval time.Duration
+
+	// Query 为空时使用 QueryNetwork
+	Query    func() (*NetworkInfo, error)
+	OnSwitch func(profile string, info *NetworkInfo, err error)
+	// Backend 为 nil 时修改系统的设置
+	Backend Backend
+}
+
+func (a *AutoSwitch) Run(ctx context.Context) error {
+	query := a.Query
+	if query == nil {
+		query = QueryNetwork
+	}
+	interval := a.Interval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	trigger := make(chan struct{}, 1)
+	var tick, retry <-chan time.Time
+	if !networkChanges(ctx, trigger) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	var fingerprint, applied string
+	for {
+		retry = nil
+		info, err := query()
+		if err == nil {
+			data, _ := json.Marshal(info)
+			if string(data) != fingerprint {
+				fingerprint = string(data)
+				name := a.Profiles.Select(info)
+				if name != "" && name != applied {
+					err := a.apply(name)
+					if err == nil {
+						applied = name
+					} else {
+						// 应用失败时在下次轮询或 Interval 后重新尝试
+						fingerprint = ""
+						retry = time.After(interval)
+					}
+					if a.OnSwitch != nil {
+						a.OnSwitch(name, info, err)
+					}
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick:
+		case <-retry:
+		case <-trigger:
+			if !debounce(ctx, trigger) {
+				return nil
+			}
+		}
+	}
+}
+
+func (a *AutoSwitch) apply(name string) error {
+	profile, err := a.Profiles.Get(name)
+	if err != nil {
+		return err
+	}
+	return orSystem(a.Backend).Apply(profile.Config(), a.Device, a.OnlyActiveDevice)
+}
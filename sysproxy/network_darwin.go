@@ -0,0 +1,73 @@
+//go:build darwin
+
+package sysproxy
+
+import (
+	"context"
+	"net/netip"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+func defaultGateways() ([]netip.Addr, error) {
+	output, err := commandOutput(exec.Command("route", "-n", "get", "default"))
+	if err != nil {
+		return nil, err
+	}
+
+	var gateways []netip.Addr
+	for line := range strings.SplitSeq(string(output), "\n") {
+		value, ok := strings.CutPrefix(strings.TrimSpace(line), "gateway:")
+		if !ok {
+			continue
+		}
+		if gateway, err := netip.ParseAddr(strings.TrimSpace(value)); err == nil {
+			gateways = append(gateways, gateway)
+		}
+	}
+	return gateways, nil
+}
+
+func searchDomains() []string {
+	return parseResolvConf("/etc/resolv.conf")
+}
+
+// networkChanges 通过路由套接字订阅接口、地址与路由的变化，无法订阅时返回 false
+func networkChanges(ctx context.Context, trigger chan<- struct{}) bool {
+	fd, err := unix.Socket(unix.AF_ROUTE, unix.SOCK_RAW, unix.AF_UNSPEC)
+	if err != nil {
+		return false
+	}
+	unix.CloseOnExec(fd)
+	if err := unix.SetNonblock(fd, true); err != nil {
+		_ = unix.Close(fd)
+		return false
+	}
+	file := os.NewFile(uintptr(fd), "route")
+
+	go func() {
+		<-ctx.Done()
+		_ = file.Close()
+	}()
+	go func() {
+		buf := make([]byte, 2048)
+		for {
+			n, err := file.Read(buf)
+			if err != nil {
+				return
+			}
+			// rtm_type 位于消息头的第 4 个字节，忽略 route get 等查询产生的 RTM_GET，避免自身查询触发检查
+			if n < 4 {
+				continue
+			}
+			switch buf[3] {
+			case unix.RTM_ADD, unix.RTM_DELETE, unix.RTM_CHANGE, unix.RTM_NEWADDR, unix.RTM_DELADDR, unix.RTM_IFINFO:
+				notify(trigger)
+			}
+		}
+	}()
+	return true
+}
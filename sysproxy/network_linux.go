@@ -0,0 +1,81 @@
+//go:build linux
+
+package sysproxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net/netip"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+func defaultGateways() ([]netip.Addr, error) {
+	file, err := os.Open("/proc/net/route")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var gateways []netip.Addr
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Iface Destination Gateway Flags ...，地址为小端序的十六进制
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+		raw, err := hex.DecodeString(fields[2])
+		if err != nil || len(raw) != 4 {
+			continue
+		}
+		var ip [4]byte
+		binary.BigEndian.PutUint32(ip[:], binary.LittleEndian.Uint32(raw))
+		if gateway := netip.AddrFrom4(ip); !gateway.IsUnspecified() {
+			gateways = append(gateways, gateway)
+		}
+	}
+	return gateways, scanner.Err()
+}
+
+func searchDomains() []string {
+	return parseResolvConf("/etc/resolv.conf")
+}
+
+// networkChanges 通过 netlink 订阅接口、地址与路由的变化，并监听 /etc/resolv.conf，
+// 无法订阅时返回 false
+func networkChanges(ctx context.Context, trigger chan<- struct{}) bool {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, unix.NETLINK_ROUTE)
+	if err != nil {
+		return false
+	}
+	groups := unix.RTMGRP_LINK | unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV6_IFADDR | unix.RTMGRP_IPV4_ROUTE | unix.RTMGRP_IPV6_ROUTE
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: uint32(groups)}); err != nil {
+		_ = unix.Close(fd)
+		return false
+	}
+	file := os.NewFile(uintptr(fd), "netlink")
+
+	go func() {
+		<-ctx.Done()
+		_ = file.Close()
+	}()
+	go func() {
+		buf := make([]byte, 1<<16)
+		for {
+			_, err := file.Read(buf)
+			// 消息过多时内核丢弃部分消息并返回 ENOBUFS，此时仍需重新检查网络
+			if err != nil && !errors.Is(err, unix.ENOBUFS) {
+				return
+			}
+			notify(trigger)
+		}
+	}()
+	_ = watchFiles(ctx, trigger, "/etc/resolv.conf")
+	return true
+}
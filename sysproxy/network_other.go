@@ -0,0 +1,20 @@
+//go:build !darwin && !linux && !windows
+
+package sysproxy
+
+import (
+	"context"
+	"net/netip"
+)
+
+func defaultGateways() ([]netip.Addr, error) {
+	return nil, nil
+}
+
+func searchDomains() []string {
+	return parseResolvConf("/etc/resolv.conf")
+}
+
+func networkChanges(_ context.Context, _ chan<- struct{}) bool {
+	return false
+}
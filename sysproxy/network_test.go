@@ -0,0 +1,120 @@
+package sysproxy
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func testNetwork() *NetworkInfo {
+	return &NetworkInfo{
+		Interfaces: []Interface{
+			{Name: "eth0", Addrs: []netip.Prefix{netip.MustParsePrefix("10.8.1.20/16")}},
+			{Name: "wlan0", Addrs: []netip.Prefix{netip.MustParsePrefix("192.168.1.5/24"), netip.MustParsePrefix("fd00::5/64")}},
+		},
+		Gateways:      []netip.Addr{netip.MustParseAddr("192.168.1.1")},
+		SearchDomains: []string{"corp.example.com.", "example.org"},
+	}
+}
+
+func TestRuleMatch(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		want bool
+	}{
+		{"gateway", Rule{Gateway: "192.168.1.1"}, true},
+		{"other gateway", Rule{Gateway: "10.0.0.1"}, false},
+		{"invalid gateway", Rule{Gateway: "not-an-ip"}, false},
+		{"subnet", Rule{Subnet: "10.8.0.0/16"}, true},
+		{"ipv6 subnet", Rule{Subnet: "fd00::/8"}, true},
+		{"other subnet", Rule{Subnet: "172.16.0.0/12"}, false},
+		{"interface", Rule{Interface: "wlan0"}, true},
+		{"missing interface", Rule{Interface: "en0"}, false},
+		{"subnet on interface", Rule{Subnet: "192.168.1.0/24", Interface: "wlan0"}, true},
+		{"subnet on other interface", Rule{Subnet: "10.8.0.0/16", Interface: "wlan0"}, false},
+		{"dns search", Rule{DNSSearch: "corp.example.com"}, true},
+		{"dns search case and trailing dot", Rule{DNSSearch: "Example.ORG."}, true},
+		{"other dns search", Rule{DNSSearch: "example.net"}, false},
+		{"all conditions", Rule{Gateway: "192.168.1.1", Subnet: "192.168.1.0/24", Interface: "wlan0", DNSSearch: "example.org"}, true},
+		{"one condition fails", Rule{Gateway: "192.168.1.1", DNSSearch: "example.net"}, false},
+		{"no conditions", Rule{}, true},
+	}
+	info := testNetwork()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Match(info); got != tt.want {
+				t.Errorf("Match(%+v) = %v, want %v", tt.rule, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchRuleFirstWins(t *testing.T) {
+	rules := []Rule{
+		{Gateway: "10.0.0.1", Profile: "office"},
+		{Subnet: "192.168.1.0/24", Profile: "home"},
+		{Interface: "wlan0", Profile: "wifi"},
+	}
+	if rule := MatchRule(rules, testNetwork()); rule == nil || rule.Profile != "home" {
+		t.Errorf("MatchRule = %+v, want home", rule)
+	}
+	if rule := MatchRule(rules[:1], testNetwork()); rule != nil {
+		t.Errorf("MatchRule = %+v, want nil", rule)
+	}
+}
+
+func TestAutoSwitch(t *testing.T) {
+	profiles := &ProfileFile{
+		Profiles: map[string]*Profile{
+			"home":   {Mode: ModeProxy, Server: "127.0.0.1:7890"},
+			"wifi":   {Mode: ModePAC, PacURL: "http://127.0.0.1/proxy.pac"},
+			"direct": {Mode: ModeDirect},
+		},
+		Rules: []Rule{
+			{Gateway: "10.0.0.1", Profile: "direct"},
+			{Subnet: "192.168.1.0/24", Profile: "home"},
+			{Interface: "wlan0", Profile: "wifi"},
+		},
+		Default: "direct",
+	}
+	backend := &memoryBackend{}
+	switched := make(chan string, 1)
+	auto := &AutoSwitch{
+		Profiles: profiles,
+		Interval: 10 * time.Millisecond,
+		Query:    func() (*NetworkInfo, error) { return testNetwork(), nil },
+		Backend:  backend,
+		OnSwitch: func(profile string, _ *NetworkInfo, err error) {
+			if err != nil {
+				t.Errorf("switch to %s: %v", profile, err)
+			}
+			select {
+			case switched <- profile:
+			default:
+			}
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- auto.Run(ctx) }()
+
+	select {
+	case profile := <-switched:
+		if profile != "home" {
+			t.Errorf("switched to %s, want home", profile)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for switch")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if config, _ := backend.Query("", false); config.Mode() != ModeProxy || config.Server() != "127.0.0.1:7890" {
+		t.Errorf("applied %+v, want home profile", config)
+	}
+}
@@ -0,0 +1,77 @@
+//go:build windows
+
+package sysproxy
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+func adapterAddresses() ([]*windows.IpAdapterAddresses, error) {
+	size := uint32(15 * 1024)
+	for {
+		buf := make([]byte, size)
+		first := (*windows.IpAdapterAddresses)(unsafe.Pointer(&buf[0]))
+		err := windows.GetAdaptersAddresses(windows.AF_UNSPEC, windows.GAA_FLAG_INCLUDE_GATEWAYS, 0, first, &size)
+		if err == windows.ERROR_BUFFER_OVERFLOW {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var adapters []*windows.IpAdapterAddresses
+		for a := first; a != nil; a = a.Next {
+			if a.OperStatus == windows.IfOperStatusUp {
+				adapters = append(adapters, a)
+			}
+		}
+		return adapters, nil
+	}
+}
+
+func defaultGateways() ([]netip.Addr, error) {
+	adapters, err := adapterAddresses()
+	if err != nil {
+		return nil, err
+	}
+
+	var gateways []netip.Addr
+	for _, a := range adapters {
+		for g := a.FirstGatewayAddress; g != nil; g = g.Next {
+			if gateway, ok := netip.AddrFromSlice(g.Address.IP()); ok {
+				gateways = append(gateways, gateway.Unmap())
+			}
+		}
+	}
+	return gateways, nil
+}
+
+func searchDomains() []string {
+	adapters, err := adapterAddresses()
+	if err != nil {
+		return nil
+	}
+
+	var domains []string
+	for _, a := range adapters {
+		if suffix := windows.UTF16PtrToString(a.DnsSuffix); suffix != "" && !slices.Contains(domains, suffix) {
+			domains = append(domains, suffix)
+		}
+		for s := a.FirstDnsSuffix; s != nil; s = s.Next {
+			if suffix := windows.UTF16ToString(s.String[:]); suffix != "" && !slices.Contains(domains, suffix) {
+				domains = append(domains, suffix)
+			}
+		}
+	}
+	return domains
+}
+
+// networkChanges 在 Windows 下未实现，按 Interval 轮询
+func networkChanges(_ context.Context, _ chan<- struct{}) bool {
+	return false
+}
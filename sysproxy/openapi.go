@@ -0,0 +1,232 @@
+//go:build darwin || linux
+
+package sysproxy
+
+import (
+	"iter"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/xishang0128/sysproxy-go/i18n"
+)
+
+func openAPI(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, openAPIDocument())
+}
+
+// openAPIDocument 根据 v1Routes 生成 OpenAPI 3 文档
+func openAPIDocument() map[string]any {
+	schemas := map[string]any{}
+	paths := map[string]map[string]any{}
+	for _, route := range v1Routes {
+		op := map[string]any{
+			"summary":     i18n.T(route.summary),
+			"operationId": strings.ToLower(route.method) + strings.ReplaceAll(route.pattern, "/", "_"),
+			"parameters":  queryParameters(reflect.TypeOf(route.params), schemas),
+			"responses":   apiResponses(route, schemas),
+		}
+		if route.method == http.MethodPost {
+			op["requestBody"] = map[string]any{
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": schemaOf(reflect.TypeOf(route.params), schemas)},
+				},
+			}
+		}
+		path := "/v1" + route.pattern
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][strings.ToLower(route.method)] = op
+	}
+	paths["/v1/events"] = map[string]any{
+		"get": map[string]any{
+			"summary":     i18n.T("订阅代理设置变化"),
+			"description": i18n.T("Server-Sent Events 流，连接后先发送 snapshot 事件，之后每次变化发送 change 事件，data 均为 StateEvent"),
+			"operationId": "get_events",
+			"parameters":  queryParameters(reflect.TypeFor[TargetParams](), schemas),
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "OK",
+					"content":     map[string]any{"text/event-stream": map[string]any{"schema": schemaOf(reflect.TypeFor[StateEvent](), schemas)}},
+				},
+				"400":     errorResponse("参数无效"),
+				"default": errorResponse("执行失败"),
+			},
+		},
+	}
+	paths["/v1/openapi.json"] = map[string]any{
+		"get": map[string]any{
+			"summary":     i18n.T("获取 OpenAPI 文档"),
+			"operationId": "get_openapi",
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "OK",
+					"content":     map[string]any{"application/json": map[string]any{"schema": map[string]any{"type": "object"}}},
+				},
+			},
+		},
+	}
+	schemaOf(reflect.TypeOf(APIError{}), schemas)
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "sysproxy",
+			"description": i18n.T("系统代理设置服务，TCP 监听时需要携带令牌"),
+			"version":     Version,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"bearer": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []map[string]any{{}, {"bearer": []string{}}},
+	}
+}
+
+func queryParameters(t reflect.Type, schemas map[string]any) []map[string]any {
+	params := []map[string]any{}
+	for field := range paramFields(reflect.New(t).Elem()) {
+		name, _ := jsonName(field)
+		param := map[string]any{
+			"name":   name,
+			"in":     "query",
+			"schema": schemaOf(field.Type, schemas),
+		}
+		if field.Type.Kind() == reflect.Map {
+			param["style"] = "deepObject"
+			param["explode"] = true
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+func apiResponses(route apiRoute, schemas map[string]any) map[string]any {
+	responses := map[string]any{
+		"400":     errorResponse("参数无效"),
+		"default": errorResponse("执行失败"),
+	}
+	if route.method != http.MethodGet {
+		responses["409"] = errorResponse("保持或看门狗正在运行")
+	}
+	if route.result == nil {
+		responses["204"] = map[string]any{"description": "No Content"}
+		return responses
+	}
+	responses["200"] = map[string]any{
+		"description": "OK",
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": schemaOf(reflect.TypeOf(route.result), schemas)},
+		},
+	}
+	return responses
+}
+
+func errorResponse(description string) map[string]any {
+	return map[string]any{
+		"description": i18n.T(description),
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/APIError"}},
+		},
+	}
+}
+
+// schemaOf 返回类型的 JSON Schema，具名结构体放入 schemas 并返回引用
+func schemaOf(t reflect.Type, schemas map[string]any) map[string]any {
+	switch t {
+	case reflect.TypeFor[time.Time]():
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaOf(t.Elem(), schemas)
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(t.Elem(), schemas)}
+	case reflect.Struct:
+		if t.Name() != "" {
+			if _, ok := schemas[t.Name()]; !ok {
+				schemas[t.Name()] = map[string]any{}
+				schemas[t.Name()] = structSchema(t, schemas)
+			}
+			return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+		}
+		return structSchema(t, schemas)
+	default:
+		return map[string]any{}
+	}
+}
+
+func structSchema(t reflect.Type, schemas map[string]any) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	for field := range paramFields(reflect.New(t).Elem()) {
+		name, omitempty := jsonName(field)
+		properties[name] = schemaOf(field.Type, schemas)
+		if !omitempty {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// paramFields 遍历结构体按 JSON 编码的字段，展开匿名嵌入的结构体
+func paramFields(v reflect.Value) iter.Seq2[reflect.StructField, reflect.Value] {
+	return func(yield func(reflect.StructField, reflect.Value) bool) {
+		t := v.Type()
+		for i := range t.NumField() {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+				for f, fv := range paramFields(v.Field(i)) {
+					if !yield(f, fv) {
+						return
+					}
+				}
+				continue
+			}
+			if name, _ := jsonName(field); name == "" {
+				continue
+			}
+			if !yield(field, v.Field(i)) {
+				return
+			}
+		}
+	}
+}
+
+// jsonName 返回字段的 JSON 名称，字段被忽略时返回空
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, strings.Contains(opts, "omitempty")
+}
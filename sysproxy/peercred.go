@@ -0,0 +1,85 @@
+//go:build darwin || linux
+
+package sysproxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+type peerCredKey struct{}
+
+// PeerCredFrom 返回请求对端的身份，无法获取时为 nil
+func PeerCredFrom(ctx context.Context) *PeerCred {
+	cred, _ := ctx.Value(peerCredKey{}).(*PeerCred)
+	return cred
+}
+
+// connContext 记录连接的来源，unix 套接字记录对端身份
+func connContext(ctx context.Context, c net.Conn) context.Context {
+	if _, ok := c.(*net.TCPConn); ok {
+		return context.WithValue(ctx, tcpConnKey{}, c.RemoteAddr().String())
+	}
+	uc, ok := c.(*net.UnixConn)
+	if !ok {
+		return ctx
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return ctx
+	}
+	var cred *PeerCred
+	_ = raw.Control(func(fd uintptr) {
+		cred, err = readPeerCred(int(fd))
+	})
+	if err != nil {
+		logger().Warn("read peer credentials failed", "error", err)
+		return ctx
+	}
+	return context.WithValue(ctx, peerCredKey{}, cred)
+}
+
+// auditCaller 返回审计日志中记录的调用方，无法确定时为 nil
+func auditCaller(ctx context.Context) *AuditCaller {
+	if remote := tcpRemote(ctx); remote != "" {
+		return &AuditCaller{Remote: remote}
+	}
+	if cred := PeerCredFrom(ctx); cred != nil {
+		return &AuditCaller{PeerCred: cred}
+	}
+	return nil
+}
+
+// authorize 要求 TCP 请求携带令牌，并拒绝不符合策略的 unix 套接字对端调用 GET 以外的接口
+func authorize(policy PeerPolicy, token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if viaTCP(r.Context()) {
+				if !validToken(r, token) {
+					logger().Warn("invalid token", "method", r.Method, "path", r.URL.Path, "remote", r.RemoteAddr)
+					w.Header().Set("WWW-Authenticate", "Bearer")
+					sendHTTPError(w, r, newError(CodeUnauthorized, "令牌无效"))
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			cred := PeerCredFrom(r.Context())
+			if !policy.Allows(cred) {
+				args := []any{"method", r.Method, "path", r.URL.Path}
+				if cred != nil {
+					args = append(args, "pid", cred.PID, "uid", cred.UID, "gid", cred.GID, "exe", cred.Exe)
+				}
+				logger().Warn("peer denied", args...)
+				sendHTTPError(w, r, newError(CodeForbidden, "无权执行此操作"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
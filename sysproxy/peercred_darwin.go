@@ -0,0 +1,36 @@
+package sysproxy
+
+import (
+	"bytes"
+
+	"golang.org/x/sys/unix"
+)
+
+func readPeerCred(fd int) (*PeerCred, error) {
+	xucred, err := unix.GetsockoptXucred(fd, unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+	if err != nil {
+		return nil, err
+	}
+	cred := &PeerCred{UID: int(xucred.Uid)}
+	for _, gid := range xucred.Groups[:min(int(xucred.Ngroups), len(xucred.Groups))] {
+		cred.Groups = append(cred.Groups, int(gid))
+	}
+	if len(cred.Groups) > 0 {
+		cred.GID = cred.Groups[0]
+	}
+	if pid, err := unix.GetsockoptInt(fd, unix.SOL_LOCAL, unix.LOCAL_PEERPID); err == nil {
+		cred.PID = pid
+		cred.Exe = procExecutable(pid)
+	}
+	return cred, nil
+}
+
+// procExecutable 从 kern.procargs2 读取进程的可执行文件路径，其格式为 argc 后接以 NUL 结尾的路径
+func procExecutable(pid int) string {
+	buf, err := unix.SysctlRaw("kern.procargs2", pid)
+	if err != nil || len(buf) < 4 {
+		return ""
+	}
+	path, _, _ := bytes.Cut(buf[4:], []byte{0})
+	return string(path)
+}
@@ -0,0 +1,51 @@
+package sysproxy
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+func readPeerCred(fd int) (*PeerCred, error) {
+	ucred, err := unix.GetsockoptUcred(fd, unix.SOL_SOCKET, unix.SO_PEERCRED)
+	if err != nil {
+		return nil, err
+	}
+	cred := &PeerCred{
+		PID: int(ucred.Pid),
+		UID: int(ucred.Uid),
+		GID: int(ucred.Gid),
+	}
+	cred.Exe, _ = os.Readlink(fmt.Sprintf("/proc/%d/exe", cred.PID))
+	cred.Groups = procGroups(cred.PID)
+	return cred, nil
+}
+
+// procGroups 读取 /proc/<pid>/status 中的附加组
+func procGroups(pid int) []int {
+	file, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "Groups:")
+		if !ok {
+			continue
+		}
+		var groups []int
+		for _, field := range strings.Fields(value) {
+			if gid, err := strconv.Atoi(field); err == nil {
+				groups = append(groups, gid)
+			}
+		}
+		return groups
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package sysproxy
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+type Profile struct {
+	Mode Mode `json:"mode"`
+	// Server 为所有协议使用同一地址，Servers 按 http、https、socks、ftp 分别指定
+	Server  string            `json:"server,omitempty"`
+	Servers map[string]string `json:"servers,omitempty"`
+	Bypass  string            `json:"bypass,omitempty"`
+	PacURL  string            `json:"pac_url,omitempty"`
+}
+
+type ProfileFile struct {
+	Profiles map[string]*Profile `json:"profiles"`
+	// Rules 按顺序匹配网络环境，均不匹配时使用 Default
+	Rules   []Rule `json:"rules,omitempty"`
+	Default string `json:"default,omitempty"`
+}
+
+func DefaultProfilesPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sysproxy", "profiles.json"), nil
+}
+
+func LoadProfiles(path string) (*ProfileFile, error) {
+	if path == "" {
+		var err error
+		if path, err = DefaultProfilesPath(); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file := &ProfileFile{}
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, newError(CodeInvalidInput, "解析配置文件 %s 失败：%v", path, err)
+	}
+	for name, profile := range file.Profiles {
+		if err := profile.Validate(); err != nil {
+			return nil, newError(CodeInvalidInput, "配置 %s 无效：%v", name, err)
+		}
+	}
+	for i := range file.Rules {
+		if err := file.Rules[i].Validate(); err != nil {
+			return nil, newError(CodeInvalidInput, "第 %d 条规则无效：%v", i+1, err)
+		}
+		if _, err := file.Get(file.Rules[i].Profile); err != nil {
+			return nil, newError(CodeInvalidInput, "第 %d 条规则无效：%v", i+1, err)
+		}
+	}
+	if file.Default != "" {
+		if _, err := file.Get(file.Default); err != nil {
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+// Select 返回网络环境对应的配置名称
+func (f *ProfileFile) Select(info *NetworkInfo) string {
+	if rule := MatchRule(f.Rules, info); rule != nil {
+		return rule.Profile
+	}
+	return f.Default
+}
+
+func (f *ProfileFile) Names() []string {
+	var names []string
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (f *ProfileFile) Get(name string) (*Profile, error) {
+	profile, ok := f.Profiles[name]
+	if !ok {
+		return nil, newError(CodeInvalidInput, "未找到配置：%s", name)
+	}
+	return profile, nil
+}
+
+// Match 返回与当前设置一致的第一个配置名称
+func (f *ProfileFile) Match(config *ProxyConfig) string {
+	for _, name := range f.Names() {
+		if len(Drift(f.Profiles[name].Config(), config)) == 0 {
+			return name
+		}
+	}
+	return ""
+}
+
+func (p *Profile) Validate() error {
+	switch p.Mode {
+	case ModeDirect:
+	case ModePAC:
+		if p.PacURL == "" {
+			return newError(CodeInvalidInput, "pac 模式需要指定 pac_url")
+		}
+		if !validURL(p.PacURL) {
+			return newError(CodeInvalidInput, "无效的 URL：%s", p.PacURL)
+		}
+	case ModeProxy:
+		if p.Server == "" && len(p.Servers) == 0 {
+			return newError(CodeInvalidInput, "proxy 模式需要指定 server 或 servers")
+		}
+		if p.Server != "" && !validServer(p.Server) {
+			return newError(CodeInvalidInput, "无效的代理地址：%s", p.Server)
+		}
+		for protocol, server := range p.Servers {
+			if _, ok := profileServerKeys[protocol]; !ok {
+				return newError(CodeInvalidInput, "未知的协议：%s", protocol)
+			}
+			if !validServer(server) {
+				return newError(CodeInvalidInput, "无效的代理地址：%s", server)
+			}
+		}
+	default:
+		return newError(CodeInvalidInput, "未知的模式：%s", p.Mode)
+	}
+	return nil
+}
+
+var profileServerKeys = map[string]string{
+	"http":  "http_server",
+	"https": "https_server",
+	"socks": "socks_server",
+	"ftp":   "ftp_server",
+}
+
+func (p *Profile) Config() *ProxyConfig {
+	switch p.Mode {
+	case ModePAC:
+		return NewPacConfig(p.PacURL)
+	case ModeProxy:
+		if len(p.Servers) == 0 {
+			return NewProxyConfig(p.Server, p.Bypass)
+		}
+		config := &ProxyConfig{}
+		config.Proxy.Enable = true
+		config.Proxy.Servers = map[string]string{}
+		for protocol, server := range p.Servers {
+			config.Proxy.Servers[profileServerKeys[protocol]] = server
+		}
+		config.Proxy.Bypass = p.Bypass
+		return config
+	default:
+		return &ProxyConfig{}
+	}
+}
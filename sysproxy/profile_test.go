@@ -0,0 +1,40 @@
+package sysproxy
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestProfileValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile Profile
+		valid   bool
+	}{
+		{"direct", Profile{Mode: ModeDirect}, true},
+		{"pac", Profile{Mode: ModePAC, PacURL: "http://127.0.0.1/proxy.pac"}, true},
+		{"pac file", Profile{Mode: ModePAC, PacURL: "file:///etc/proxy.pac"}, true},
+		{"pac without url", Profile{Mode: ModePAC}, false},
+		{"pac invalid url", Profile{Mode: ModePAC, PacURL: "proxy.pac"}, false},
+		{"proxy", Profile{Mode: ModeProxy, Server: "127.0.0.1:7890"}, true},
+		{"proxy with scheme", Profile{Mode: ModeProxy, Server: "socks5://127.0.0.1:1080"}, true},
+		{"proxy without server", Profile{Mode: ModeProxy}, false},
+		{"proxy without port", Profile{Mode: ModeProxy, Server: "127.0.0.1"}, false},
+		{"proxy invalid port", Profile{Mode: ModeProxy, Server: "127.0.0.1:70000"}, false},
+		{"servers", Profile{Mode: ModeProxy, Servers: map[string]string{"http": "127.0.0.1:8080", "socks": "127.0.0.1:1080"}}, true},
+		{"servers unknown protocol", Profile{Mode: ModeProxy, Servers: map[string]string{"gopher": "127.0.0.1:70"}}, false},
+		{"servers invalid address", Profile{Mode: ModeProxy, Servers: map[string]string{"http": "localhost"}}, false},
+		{"unknown mode", Profile{Mode: "auto"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.profile.Validate()
+			if (err == nil) != tt.valid {
+				t.Errorf("Validate() = %v, want valid %v", err, tt.valid)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidInput) {
+				t.Errorf("Validate() = %v, want ErrInvalidInput", err)
+			}
+		})
+	}
+}
@@ -1,10 +1,9 @@
-//go:build darwin
+//go:build darwin || linux
 
 package sysproxy
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 	"time"
 
@@ -22,11 +21,16 @@ type Request struct {
 }
 
 type Response struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
+	Status  string    `json:"status"`
+	Message string    `json:"message"`
+	Code    ErrorCode `json:"code,omitempty"`
 }
 
-func router() *chi.Mux {
+func (req *Request) target() TargetParams {
+	return TargetParams{Device: req.Device, OnlyActiveDevice: req.OnlyActiveDevice}
+}
+
+func router(opts ServerOptions) *chi.Mux {
 	r := chi.NewRouter()
 	r.Get("/status", status)
 	r.Get("/ping", func(w http.ResponseWriter, r *http.Request) {
@@ -35,13 +39,24 @@ func router() *chi.Mux {
 	r.Post("/pac", pac)
 	r.Post("/proxy", proxy)
 	r.Post("/disable", disable)
+	r.Get("/guard", guardStatus)
+	r.Post("/guard", startGuard)
+	r.Delete("/guard", deleteGuard)
+	r.Get("/watchdog", watchdogStatus)
+	r.Post("/watchdog", startWatchdog)
+	r.Delete("/watchdog", deleteWatchdog)
+	r.Mount("/v1", v1Router())
+	if opts.Metrics {
+		r.Get("/metrics", metrics)
+	}
 	return r
 }
 
 func status(w http.ResponseWriter, r *http.Request) {
 	t := time.Now()
-	status, err := QueryProxySettings("", true)
-	log.Println("QueryProxySettings took:", time.Since(t))
+	status, err := backend().Query("", true)
+	logCall("query proxy settings", t, err)
+	observeQuery("status", t, err)
 	if err != nil {
 		sendError(w, err)
 		return
@@ -56,9 +71,7 @@ func pac(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	t := time.Now()
-	err := SetPac(req.Url, req.Device, req.OnlyActiveDevice)
-	log.Println("SetPac took:", time.Since(t), "\nURL:", req.Url)
+	_, err := applyQueued(r.Context(), "pac", NewPacConfig(req.Url), req.target(), true)
 	if err != nil {
 		sendError(w, err)
 		return
@@ -73,9 +86,7 @@ func proxy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	t := time.Now()
-	err := SetProxy(req.Server, req.Bypass, req.Device, req.OnlyActiveDevice)
-	log.Println("SetProxy took:", time.Since(t), "\nserver:", req.Server, "\nbypass:", req.Bypass)
+	_, err := applyQueued(r.Context(), "proxy", NewProxyConfig(req.Server, req.Bypass), req.target(), true)
 	if err != nil {
 		sendError(w, err)
 		return
@@ -90,9 +101,7 @@ func disable(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	t := time.Now()
-	err := DisableProxy(req.Device, req.OnlyActiveDevice)
-	log.Println("DisableProxy took:", time.Since(t))
+	_, err := applyQueued(r.Context(), "disable", &ProxyConfig{}, req.target(), true)
 	if err != nil {
 		sendError(w, err)
 		return
@@ -108,14 +117,26 @@ func decodeRequest(r *http.Request, v any) error {
 }
 
 func sendJSON(w http.ResponseWriter, status string, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	resp := Response{
+	sendResponse(w, http.StatusOK, Response{
 		Status:  status,
 		Message: message,
-	}
+	})
+}
+
+func sendResponse(w http.ResponseWriter, code int, resp Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
 	json.NewEncoder(w).Encode(resp)
 }
 
 func sendError(w http.ResponseWriter, err error) {
-	sendJSON(w, "error", err.Error())
+	sendErrorStatus(w, http.StatusOK, err)
+}
+
+func sendErrorStatus(w http.ResponseWriter, code int, err error) {
+	sendResponse(w, code, Response{
+		Status:  "error",
+		Message: err.Error(),
+		Code:    CodeOf(err),
+	})
 }
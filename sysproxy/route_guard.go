@@ -0,0 +1,126 @@
+//go:build darwin || linux
+
+package sysproxy
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+var guardState struct {
+	sync.Mutex
+	guard  *Guard
+	cancel context.CancelFunc
+}
+
+func startGuard(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	if err := decodeRequest(r, &req); err != nil {
+		sendError(w, err)
+		return
+	}
+
+	// 在停止已有的保持与看门狗之前检查参数，无效的请求不影响它们
+	var err error
+	switch {
+	case req.Server == "" && req.Url == "":
+		err = newError(CodeInvalidInput, "需要指定 server 或 url")
+	case req.Url != "" && !validURL(req.Url):
+		err = newError(CodeInvalidInput, "无效的 URL：%s", req.Url)
+	case req.Url == "" && !validServer(req.Server):
+		err = newError(CodeInvalidInput, "无效的代理地址：%s", req.Server)
+	}
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+
+	p := GuardParams{Server: req.Server, Bypass: req.Bypass, URL: req.Url, TargetParams: req.target()}
+	err = mutate(r.Context(), "guard_start", p.TargetParams, func() error {
+		stopBackground()
+		_, err := runGuard(p)
+		return err
+	})
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+	render.NoContent(w, r)
+}
+
+func deleteGuard(w http.ResponseWriter, r *http.Request) {
+	err := mutate(r.Context(), "guard_stop", TargetParams{}, func() error {
+		stopGuard()
+		return nil
+	})
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+	render.NoContent(w, r)
+}
+
+func guardStatus(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, currentGuard())
+}
+
+// runGuard 应用设置并在后台保持，应在修改队列中调用并先停止已有的保持与看门狗
+func runGuard(p GuardParams) (*Guard, error) {
+	desired := p.Config()
+	t := time.Now()
+	err := backend().Apply(desired, p.Device, p.OnlyActiveDevice)
+	logCall("guard apply", t, err, "mode", desired.Mode())
+	observeApply("guard", t, err)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	guard := &Guard{
+		Desired:          desired,
+		Device:           p.Device,
+		OnlyActiveDevice: p.OnlyActiveDevice,
+		Backend:          queuedBackend{ctx: ctx, apply: "guard_correct", restore: "guard_correct", target: p.TargetParams},
+		OnCorrect: func(c Correction) {
+			observeGuardCorrection(c.Error)
+			if c.Error != "" {
+				logger().Error("guard restore failed", "diff", c.Diff, "error", c.Error)
+				return
+			}
+			logger().Info("guard restored", "diff", c.Diff)
+		},
+	}
+	guardState.Lock()
+	guardState.guard = guard
+	guardState.cancel = cancel
+	guardState.Unlock()
+
+	go guard.Hold(ctx)
+	return guard, nil
+}
+
+func currentGuard() GuardInfo {
+	guardState.Lock()
+	guard := guardState.guard
+	guardState.Unlock()
+
+	if guard == nil {
+		return GuardInfo{}
+	}
+	status := guard.Status()
+	return GuardInfo{Active: true, Guard: &status}
+}
+
+func stopGuard() {
+	guardState.Lock()
+	defer guardState.Unlock()
+	if guardState.cancel != nil {
+		guardState.cancel()
+	}
+	guardState.guard = nil
+	guardState.cancel = nil
+}
@@ -0,0 +1,354 @@
+//go:build darwin || linux
+
+package sysproxy
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// 仅用于 /v1 路由本身的错误码
+const (
+	codeNotFound         ErrorCode = "not_found"
+	codeMethodNotAllowed ErrorCode = "method_not_allowed"
+)
+
+// apiRoute 描述一个 /v1 接口，OpenAPI 文档由同一张表生成
+type apiRoute struct {
+	method  string
+	pattern string
+	summary string
+	// params 与 result 为零值示例，result 为 nil 时返回 204
+	params  any
+	result  any
+	handler http.HandlerFunc
+}
+
+var v1Routes = []apiRoute{
+	{http.MethodGet, "/ping", "检查服务是否可用并获取版本", struct{}{}, ServerInfo{}, v1Ping},
+	{http.MethodGet, "/status", "查询代理设置", TargetParams{}, ProxyConfig{}, v1Status},
+	{http.MethodPost, "/proxy", "设置系统代理", ProxyParams{}, ProxyConfig{}, v1Proxy},
+	{http.MethodPost, "/pac", "设置 PAC 代理", PacParams{}, ProxyConfig{}, v1Pac},
+	{http.MethodPost, "/disable", "取消代理设置", DisableParams{}, ProxyConfig{}, v1Disable},
+	{http.MethodGet, "/guard", "查看保持状态", struct{}{}, GuardInfo{}, v1GuardStatus},
+	{http.MethodPost, "/guard", "应用并保持代理设置", GuardParams{}, GuardInfo{}, v1StartGuard},
+	{http.MethodDelete, "/guard", "停止保持", struct{}{}, nil, v1StopGuard},
+	{http.MethodGet, "/watchdog", "查看看门狗状态", struct{}{}, WatchdogInfo{}, v1WatchdogStatus},
+	{http.MethodPost, "/watchdog", "设置代理并启动看门狗", WatchdogParams{}, WatchdogInfo{}, v1StartWatchdog},
+	{http.MethodDelete, "/watchdog", "停止看门狗", struct{}{}, nil, v1StopWatchdog},
+	{http.MethodGet, "/lease", "查看租约", struct{}{}, []LeaseInfo{}, v1Leases},
+	{http.MethodPost, "/lease", "创建或续期租约", LeaseParams{}, LeaseInfo{}, v1AcquireLease},
+	{http.MethodPost, "/lease/heartbeat", "续期租约", LeaseHeartbeat{}, LeaseInfo{}, v1HeartbeatLease},
+	{http.MethodDelete, "/lease", "释放租约", LeaseRelease{}, nil, v1ReleaseLease},
+}
+
+func v1Router() chi.Router {
+	r := chi.NewRouter()
+	for _, route := range v1Routes {
+		r.Method(route.method, route.pattern, route.handler)
+	}
+	r.Get("/events", v1Events)
+	r.Get("/openapi.json", openAPI)
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		sendAPIError(w, newError(codeNotFound, "接口不存在：%s", r.URL.Path))
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		sendAPIError(w, newError(codeMethodNotAllowed, "不支持的请求方法：%s", r.Method))
+	})
+	return r
+}
+
+func v1Ping(w http.ResponseWriter, r *http.Request) {
+	var p struct{}
+	if err := decodeParams(r, &p); err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	render.JSON(w, r, ServerInfo{Version: Version, API: APIVersion})
+}
+
+func v1Status(w http.ResponseWriter, r *http.Request) {
+	var p TargetParams
+	if err := decodeParams(r, &p); err != nil {
+		sendAPIError(w, err)
+		return
+	}
+
+	t := time.Now()
+	status, err := backend().Query(p.Device, p.OnlyActiveDevice)
+	logCall("query proxy settings", t, err, "device", p.Device)
+	observeQuery("status", t, err)
+	if err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	render.JSON(w, r, status)
+}
+
+func v1Proxy(w http.ResponseWriter, r *http.Request) {
+	var p ProxyParams
+	if err := decodeParams(r, &p); err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	v1Apply(w, r, "proxy", p.Config(), p.Force, p.TargetParams)
+}
+
+func v1Pac(w http.ResponseWriter, r *http.Request) {
+	var p PacParams
+	if err := decodeParams(r, &p); err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	v1Apply(w, r, "pac", NewPacConfig(p.URL), p.Force, p.TargetParams)
+}
+
+func v1Disable(w http.ResponseWriter, r *http.Request) {
+	var p DisableParams
+	if err := decodeParams(r, &p); err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	v1Apply(w, r, "disable", &ProxyConfig{}, p.Force, p.TargetParams)
+}
+
+func v1Apply(w http.ResponseWriter, r *http.Request, operation string, config *ProxyConfig, force bool, target TargetParams) {
+	// 请求被合并时返回最终应用的设置
+	config, err := applyQueued(r.Context(), operation, config, target, force)
+	if err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	render.JSON(w, r, config)
+}
+
+func v1GuardStatus(w http.ResponseWriter, r *http.Request) {
+	var p struct{}
+	if err := decodeParams(r, &p); err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	render.JSON(w, r, currentGuard())
+}
+
+func v1StartGuard(w http.ResponseWriter, r *http.Request) {
+	var p GuardParams
+	if err := decodeParams(r, &p); err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	err := mutate(r.Context(), "guard_start", p.TargetParams, func() error {
+		if err := takeOver(p.Force); err != nil {
+			return err
+		}
+		_, err := runGuard(p)
+		return err
+	})
+	if err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	render.JSON(w, r, currentGuard())
+}
+
+func v1StopGuard(w http.ResponseWriter, r *http.Request) {
+	var p struct{}
+	if err := decodeParams(r, &p); err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	err := mutate(r.Context(), "guard_stop", TargetParams{}, func() error {
+		stopGuard()
+		return nil
+	})
+	if err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	render.NoContent(w, r)
+}
+
+func v1WatchdogStatus(w http.ResponseWriter, r *http.Request) {
+	var p struct{}
+	if err := decodeParams(r, &p); err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	render.JSON(w, r, currentWatchdog())
+}
+
+func v1StartWatchdog(w http.ResponseWriter, r *http.Request) {
+	var p WatchdogParams
+	if err := decodeParams(r, &p); err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	watchdog, err := newWatchdog(p)
+	if err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	err = mutate(r.Context(), "watchdog_start", p.TargetParams, func() error {
+		return runWatchdog(watchdog, p, func() error {
+			return takeOver(p.Force)
+		})
+	})
+	if err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	render.JSON(w, r, currentWatchdog())
+}
+
+func v1StopWatchdog(w http.ResponseWriter, r *http.Request) {
+	var p struct{}
+	if err := decodeParams(r, &p); err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	err := mutate(r.Context(), "watchdog_stop", TargetParams{}, func() error {
+		stopWatchdog()
+		return nil
+	})
+	if err != nil {
+		sendAPIError(w, err)
+		return
+	}
+	render.NoContent(w, r)
+}
+
+// takeOver 在保持或看门狗运行时返回冲突，force 为 true 时先停止它们
+func takeOver(force bool) error {
+	if !force && backgroundRunning() {
+		return newError(CodeConflict, "保持或看门狗正在运行，指定 force 以停止它们")
+	}
+	stopBackground()
+	return nil
+}
+
+// decodeParams 依次读取 JSON 请求体与查询参数，查询参数优先，随后校验参数
+func decodeParams(r *http.Request, v any) error {
+	if r.Body != nil && r.ContentLength != 0 {
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+			return newError(CodeInvalidInput, "解析请求失败：%v", err)
+		}
+	}
+	if err := decodeQuery(r.URL.Query(), reflect.ValueOf(v).Elem()); err != nil {
+		return err
+	}
+	if p, ok := v.(interface{ Validate() error }); ok {
+		return p.Validate()
+	}
+	return nil
+}
+
+func decodeQuery(query url.Values, v reflect.Value) error {
+	errs := FieldErrors{}
+	known := map[string]bool{}
+	for field, value := range paramFields(v) {
+		name, _ := jsonName(field)
+		if value.Kind() == reflect.Map {
+			// 映射使用 deepObject 格式，如 servers[http]=127.0.0.1:7890
+			prefix := name + "["
+			for key := range query {
+				if sub, ok := strings.CutPrefix(key, prefix); ok && strings.HasSuffix(sub, "]") {
+					sub = strings.TrimSuffix(sub, "]")
+					known[key] = true
+					if value.IsNil() {
+						value.Set(reflect.MakeMap(value.Type()))
+					}
+					value.SetMapIndex(reflect.ValueOf(sub), reflect.ValueOf(query.Get(key)))
+				}
+			}
+			continue
+		}
+		known[name] = true
+		if !query.Has(name) {
+			continue
+		}
+		s := query.Get(name)
+		switch value.Kind() {
+		case reflect.String:
+			value.SetString(s)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				errs.add(name, "无效的值：%s", s)
+				continue
+			}
+			value.SetBool(b)
+		case reflect.Int:
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				errs.add(name, "无效的值：%s", s)
+				continue
+			}
+			value.SetInt(int64(n))
+		}
+	}
+	for key := range query {
+		if !known[key] {
+			errs.add(key, "未知的参数")
+		}
+	}
+	return errs.err()
+}
+
+// sendHTTPError 按请求路径选择 /v1 或旧接口的错误格式
+func sendHTTPError(w http.ResponseWriter, r *http.Request, err error) {
+	if strings.HasPrefix(r.URL.Path, "/v1/") {
+		sendAPIError(w, err)
+		return
+	}
+	sendErrorStatus(w, httpStatus(CodeOf(err)), err)
+}
+
+func sendAPIError(w http.ResponseWriter, err error) {
+	code := CodeOf(err)
+	if code == "" {
+		code = CodeSystem
+	}
+	resp := APIError{Code: code, Message: err.Error()}
+	var fields FieldErrors
+	if errors.As(err, &fields) {
+		resp.Fields = fields
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus(code))
+	json.NewEncoder(w).Encode(resp)
+}
+
+func httpStatus(code ErrorCode) int {
+	switch code {
+	case CodeInvalidInput:
+		return http.StatusBadRequest
+	case CodeUnauthorized:
+		return http.StatusUnauthorized
+	case CodeForbidden:
+		return http.StatusForbidden
+	case codeNotFound:
+		return http.StatusNotFound
+	case codeMethodNotAllowed:
+		return http.StatusMethodNotAllowed
+	case CodeConflict:
+		return http.StatusConflict
+	case CodeUnsupported:
+		return http.StatusNotImplemented
+	case CodeUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,216 @@
+//go:build darwin || linux
+
+package sysproxy
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+type WatchdogRequest struct {
+	Request
+	Probe          string `json:"probe,omitempty"`
+	Interval       string `json:"interval,omitempty"`
+	Timeout        string `json:"timeout,omitempty"`
+	Failures       int    `json:"failures,omitempty"`
+	FallbackServer string `json:"fallback_server,omitempty"`
+	FallbackUrl    string `json:"fallback_url,omitempty"`
+}
+
+var watchdogState struct {
+	sync.Mutex
+	watchdog *Watchdog
+	cancel   context.CancelFunc
+}
+
+func startWatchdog(w http.ResponseWriter, r *http.Request) {
+	var req WatchdogRequest
+	if err := decodeRequest(r, &req); err != nil {
+		sendError(w, err)
+		return
+	}
+
+	p := WatchdogParams{
+		Server:         req.Server,
+		Bypass:         req.Bypass,
+		Probe:          req.Probe,
+		Interval:       req.Interval,
+		Timeout:        req.Timeout,
+		Failures:       req.Failures,
+		FallbackServer: req.FallbackServer,
+		FallbackURL:    req.FallbackUrl,
+		TargetParams:   req.target(),
+	}
+	watchdog, err := newWatchdog(p)
+	if err != nil {
+		sendWatchdogError(w, err)
+		return
+	}
+
+	err = mutate(r.Context(), "watchdog_start", p.TargetParams, func() error {
+		return runWatchdog(watchdog, p, func() error {
+			stopBackground()
+			return nil
+		})
+	})
+	if err != nil {
+		sendWatchdogError(w, err)
+		return
+	}
+	render.NoContent(w, r)
+}
+
+// sendWatchdogError 在参数无效、看门狗未启动时返回 400，其他错误与旧接口一致返回 200
+func sendWatchdogError(w http.ResponseWriter, err error) {
+	if CodeOf(err) == CodeInvalidInput {
+		sendErrorStatus(w, http.StatusBadRequest, err)
+		return
+	}
+	sendError(w, err)
+}
+
+func deleteWatchdog(w http.ResponseWriter, r *http.Request) {
+	err := mutate(r.Context(), "watchdog_stop", TargetParams{}, func() error {
+		stopWatchdog()
+		return nil
+	})
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+	render.NoContent(w, r)
+}
+
+func watchdogStatus(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, currentWatchdog())
+}
+
+func newWatchdog(p WatchdogParams) (*Watchdog, error) {
+	watchdog := &Watchdog{
+		Device:           p.Device,
+		OnlyActiveDevice: p.OnlyActiveDevice,
+		Probe:            p.Probe,
+		Failures:         p.Failures,
+		OnStateChange: func(s WatchdogStatus) {
+			logger().Warn("watchdog state changed", "state", s.State, "endpoint", s.Endpoint, "error", s.LastError)
+		},
+	}
+	for _, d := range []struct {
+		value string
+		dst   *time.Duration
+	}{{p.Interval, &watchdog.Interval}, {p.Timeout, &watchdog.Timeout}} {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil {
+			return nil, newError(CodeInvalidInput, "无效的时间间隔：%s", d.value)
+		}
+		*d.dst = v
+	}
+	switch {
+	case p.FallbackURL != "":
+		watchdog.Fallback = NewPacConfig(p.FallbackURL)
+	case p.FallbackServer != "":
+		watchdog.Fallback = NewProxyConfig(p.FallbackServer, p.Bypass)
+	}
+	if p.Server == "" {
+		if _, err := probeFunc(p.Probe); err != nil {
+			return nil, err
+		}
+		return watchdog, nil
+	}
+	watchdog.Proxy = NewProxyConfig(p.Server, p.Bypass)
+	if _, _, err := watchdog.check(); err != nil {
+		return nil, err
+	}
+	return watchdog, nil
+}
+
+// runWatchdog 应用代理设置并在后台运行看门狗，应在修改队列中调用，
+// 未指定 server 时监视当前的代理设置，探测地址无效时在启动前返回错误，
+// 检查通过后才调用 takeOver 停止已有的保持与看门狗，被拒绝的请求不影响它们
+func runWatchdog(watchdog *Watchdog, p WatchdogParams, takeOver func() error) error {
+	if p.Server == "" {
+		current, err := backend().Query(p.Device, p.OnlyActiveDevice)
+		if err != nil {
+			return err
+		}
+		if current.Mode() != ModeProxy {
+			return newError(CodeInvalidInput, "当前未设置代理，需要指定 server")
+		}
+		watchdog.Proxy = current
+		if err := watchdog.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := takeOver(); err != nil {
+		return err
+	}
+	if p.Server != "" {
+		t := time.Now()
+		err := backend().Apply(watchdog.Proxy, p.Device, p.OnlyActiveDevice)
+		logCall("watchdog apply", t, err, "server", p.Server)
+		observeApply("watchdog", t, err)
+		if err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	watchdog.Backend = queuedBackend{ctx: ctx, apply: "watchdog_fallback", restore: "watchdog_restore", target: p.TargetParams}
+	watchdogState.Lock()
+	watchdogState.watchdog = watchdog
+	watchdogState.cancel = cancel
+	watchdogState.Unlock()
+
+	go func() {
+		if err := watchdog.Run(ctx); err != nil {
+			logger().Error("watchdog stopped", "error", err)
+		}
+	}()
+	return nil
+}
+
+func currentWatchdog() WatchdogInfo {
+	watchdogState.Lock()
+	watchdog := watchdogState.watchdog
+	watchdogState.Unlock()
+
+	if watchdog == nil {
+		return WatchdogInfo{}
+	}
+	status := watchdog.Status()
+	return WatchdogInfo{Active: true, Watchdog: &status}
+}
+
+func stopWatchdog() {
+	watchdogState.Lock()
+	defer watchdogState.Unlock()
+	if watchdogState.cancel != nil {
+		watchdogState.cancel()
+	}
+	watchdogState.watchdog = nil
+	watchdogState.cancel = nil
+}
+
+// stopBackground 停止保持与看门狗，客户端直接修改设置时它们不应再干预
+func stopBackground() {
+	stopGuard()
+	stopWatchdog()
+}
+
+// backgroundRunning 报告是否有保持或看门狗在运行
+func backgroundRunning() bool {
+	guardState.Lock()
+	guarding := guardState.guard != nil
+	guardState.Unlock()
+
+	watchdogState.Lock()
+	defer watchdogState.Unlock()
+	return guarding || watchdogState.watchdog != nil
+}
@@ -1,69 +1,388 @@
-//go:build darwin
+//go:build darwin || linux
 
 package sysproxy
 
 import (
-	"fmt"
-	"log"
+	"context"
+	"errors"
 	"net"
 	"net/http"
 	"os"
+	"os/user"
 	"path/filepath"
+	"strconv"
+	"sync"
 	"syscall"
+	"time"
 )
 
-var unixServer *http.Server
+// helperServer 为运行中的服务，停止时依次等待请求完成、停止后台任务、恢复设置并删除文件
+type helperServer struct {
+	http *http.Server
+	opts ServerOptions
+	// files 为退出时删除的套接字与令牌文件
+	files    []string
+	snapshot *ProxyConfig
+	// stopping 在开始停止时关闭，事件流等长连接据此返回
+	stopping chan struct{}
+	once     sync.Once
+	done     chan struct{}
+	err      error
+}
+
+var running struct {
+	sync.Mutex
+	server *helperServer
+}
+
+var serverBackend struct {
+	sync.RWMutex
+	backend Backend
+}
+
+// backend 返回服务读取与修改设置使用的 Backend
+func backend() Backend {
+	serverBackend.RLock()
+	defer serverBackend.RUnlock()
+	return orSystem(serverBackend.backend)
+}
 
 func Start(l string) error {
-	if err := startServer(l, StartUnix); err != nil {
-		return err
-	}
-	return nil
+	return Serve(ServerOptions{Listen: l})
 }
 
-func startServer(addr string, startFunc func(string) error) error {
-	if unixServer != nil {
-		_ = unixServer.Close()
-		unixServer = nil
+func Serve(opts ServerOptions) error {
+	if err := Stop(context.Background()); err != nil {
+		logger().Warn("stop previous server failed", "error", err)
 	}
 
-	if len(addr) > 0 {
-		dir := filepath.Dir(addr)
-		if err := ensureDirExists(dir); err != nil {
+	if len(opts.TCP) > 0 {
+		if err := checkTCPAddr(opts.TCP, opts.AllowRemote); err != nil {
 			return err
 		}
+	}
 
-		if err := syscall.Unlink(addr); err != nil && !os.IsNotExist(err) {
-			return fmt.Errorf("unlink error: %w", err)
+	listeners, err := activationListeners()
+	if err != nil {
+		return err
+	}
+	var files []string
+	if len(listeners) == 0 && len(opts.Listen) > 0 {
+		l, err := listenUnix(opts.Listen)
+		if err != nil {
+			return err
+		}
+		if err := setSocketOwner(opts); err != nil {
+			_ = l.Close()
+			return err
 		}
+		listeners = append(listeners, l)
+		if !isAbstractSocket(opts.Listen) {
+			files = append(files, opts.Listen)
+		}
+	}
 
-		if err := startFunc(addr); err != nil {
+	var token string
+	if len(opts.TCP) > 0 {
+		tokenFile := opts.TokenFile
+		if tokenFile == "" {
+			tokenFile = DefaultTokenPath()
+		}
+		var l net.Listener
+		l, token, err = listenTCP(opts.TCP, tokenFile, opts.AllowRemote)
+		if err != nil {
+			closeListeners(listeners, files)
 			return err
 		}
+		listeners = append(listeners, l)
+		files = append(files, tokenFile)
+	}
+
+	if len(listeners) == 0 {
+		return nil
+	}
+	return serve(listeners, token, files, opts)
+}
+
+// Stop 停止正在运行的服务：不再接受新连接并等待进行中的请求完成，ctx 结束时强制关闭连接，
+// 之后停止保持与看门狗，指定 RestoreOnExit 时恢复启动时的设置，并删除套接字与令牌文件
+func Stop(ctx context.Context) error {
+	running.Lock()
+	s := running.server
+	running.Unlock()
+	if s == nil {
+		return nil
+	}
+	return s.shutdown(ctx)
+}
+
+// setSocketOwner 按选项设置套接字文件的权限与属主
+func setSocketOwner(opts ServerOptions) error {
+	if isAbstractSocket(opts.Listen) {
+		return nil
+	}
+	mode := opts.SocketMode
+	if mode == 0 {
+		mode = 0o600
+	}
+	if err := os.Chmod(opts.Listen, mode); err != nil {
+		return newError(CodeSystem, "设置套接字权限失败：%v", err)
+	}
+	if opts.SocketOwner == "" && opts.SocketGroup == "" {
+		return nil
+	}
+
+	uid, gid := -1, -1
+	if opts.SocketOwner != "" {
+		id, err := lookupID(opts.SocketOwner, func(name string) (string, error) {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", err
+			}
+			return u.Uid, nil
+		})
+		if err != nil {
+			return newError(CodeInvalidInput, "未知的用户：%s", opts.SocketOwner)
+		}
+		uid = id
+	}
+	if opts.SocketGroup != "" {
+		id, err := lookupID(opts.SocketGroup, func(name string) (string, error) {
+			g, err := user.LookupGroup(name)
+			if err != nil {
+				return "", err
+			}
+			return g.Gid, nil
+		})
+		if err != nil {
+			return newError(CodeInvalidInput, "未知的用户组：%s", opts.SocketGroup)
+		}
+		gid = id
+	}
+	if err := os.Chown(opts.Listen, uid, gid); err != nil {
+		return newError(CodeSystem, "设置套接字属主失败：%v", err)
 	}
 	return nil
 }
 
+// lookupID 将名称或数字转换为 id
+func lookupID(name string, lookup func(string) (string, error)) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil {
+		return id, nil
+	}
+	id, err := lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(id)
+}
+
 func ensureDirExists(dir string) error {
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
 		if err := os.MkdirAll(dir, 0o755); err != nil {
-			return fmt.Errorf("directory creation error: %w", err)
+			return newError(CodeSystem, "创建目录失败：%v", err)
 		}
 	}
 	return nil
 }
 
 func StartUnix(addr string) error {
+	l, err := listenUnix(addr)
+	if err != nil {
+		return err
+	}
+	opts := ServerOptions{Listen: addr}
+	if err := setSocketOwner(opts); err != nil {
+		_ = l.Close()
+		return err
+	}
+	return serve([]net.Listener{l}, "", []string{addr}, opts)
+}
+
+func closeListeners(listeners []net.Listener, files []string) {
+	for _, l := range listeners {
+		_ = l.Close()
+	}
+	for _, f := range files {
+		_ = os.Remove(f)
+	}
+}
+
+func listenUnix(addr string) (net.Listener, error) {
+	if !isAbstractSocket(addr) {
+		if err := ensureDirExists(filepath.Dir(addr)); err != nil {
+			return nil, err
+		}
+		if err := syscall.Unlink(addr); err != nil && !os.IsNotExist(err) {
+			return nil, newError(CodeSystem, "删除旧的监听文件失败：%v", err)
+		}
+	}
+
 	l, err := net.Listen("unix", addr)
 	if err != nil {
-		return fmt.Errorf("unix listen error: %w", err)
+		return nil, newError(CodeSystem, "监听 unix 套接字失败：%v", err)
+	}
+	logger().Info("unix listening", "addr", l.Addr().String())
+	return l, nil
+}
+
+func serve(listeners []net.Listener, token string, files []string, opts ServerOptions) error {
+	serverBackend.Lock()
+	serverBackend.backend = opts.Backend
+	serverBackend.Unlock()
+	if opts.Allow.Empty() {
+		logger().Info("no peer policy configured, only the server user and root may change proxy settings", "uid", os.Geteuid())
+	}
+	s := &helperServer{
+		opts:     opts,
+		files:    files,
+		stopping: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if opts.RestoreOnExit {
+		snapshot, err := backend().Query("", false)
+		if err != nil {
+			closeListeners(listeners, files)
+			return err
+		}
+		s.snapshot = snapshot
+	}
+	s.http = &http.Server{
+		Handler: authorize(opts.Allow, token)(router(opts)),
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			return context.WithValue(connContext(ctx, c), stoppingKey{}, s.stopping)
+		},
+	}
+	s.http.RegisterOnShutdown(func() {
+		close(s.stopping)
+	})
+	running.Lock()
+	running.server = s
+	running.Unlock()
+
+	if opts.IdleTimeout > 0 {
+		idle := &idleTracker{last: time.Now()}
+		s.http.Handler = idle.wrap(s.http.Handler)
+		go idle.watch(s, opts.IdleTimeout)
+	}
+
+	errs := make(chan error, len(listeners))
+	var addrs []string
+	for _, l := range listeners {
+		addrs = append(addrs, l.Addr().String())
+		go func() {
+			errs <- s.http.Serve(l)
+		}()
+	}
+	sdNotify("READY=1")
+	logger().Info("server ready", "listen", addrs)
+	if opts.Ready != nil {
+		opts.Ready(addrs)
 	}
-	_ = os.Chmod(addr, 0o666)
-	log.Printf("unix listening at: %s", l.Addr().String())
 
-	server := &http.Server{
-		Handler: router(),
+	err := <-errs
+	if errors.Is(err, http.ErrServerClosed) {
+		<-s.done
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), opts.shutdownTimeout())
+	defer cancel()
+	_ = s.shutdown(ctx)
+	return err
+}
+
+func (s *helperServer) shutdown(ctx context.Context) error {
+	s.once.Do(func() {
+		defer close(s.done)
+		sdNotify("STOPPING=1")
+		logger().Info("server shutting down")
+		if err := s.http.Shutdown(ctx); err != nil {
+			logger().Warn("requests still running after shutdown timeout, closing connections", "error", err)
+			_ = s.http.Close()
+			s.err = newError(CodeSystem, "等待请求完成超时：%v", err)
+		}
+
+		stopBackground()
+		dropLeases()
+		if s.snapshot != nil {
+			t := time.Now()
+			err := restoreQueued(context.Background(), "restore_on_exit", s.snapshot, TargetParams{})
+			logCall("restore proxy settings on exit", t, err, "mode", s.snapshot.Mode())
+			if err != nil && s.err == nil {
+				s.err = err
+			}
+		}
+		for _, f := range s.files {
+			if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+				logger().Warn("remove file failed", "path", f, "error", err)
+			}
+		}
+
+		running.Lock()
+		if running.server == s {
+			running.server = nil
+		}
+		running.Unlock()
+	})
+	<-s.done
+	return s.err
+}
+
+type stoppingKey struct{}
+
+// serverStopping 返回服务开始停止时关闭的通道，长连接的处理函数应在其关闭时返回
+func serverStopping(ctx context.Context) <-chan struct{} {
+	ch, _ := ctx.Value(stoppingKey{}).(chan struct{})
+	return ch
+}
+
+// idleTracker 记录进行中的请求数与最后一次请求结束的时间
+type idleTracker struct {
+	mu     sync.Mutex
+	active int
+	last   time.Time
+}
+
+func (t *idleTracker) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.mu.Lock()
+		t.active++
+		t.mu.Unlock()
+		defer func() {
+			t.mu.Lock()
+			t.active--
+			t.last = time.Now()
+			t.mu.Unlock()
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// idle 返回已空闲的时间，有请求进行中、有后台任务或租约时为 0
+func (t *idleTracker) idle() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.active > 0 || backgroundRunning() || leasesActive() {
+		t.last = time.Now()
+		return 0
+	}
+	return time.Since(t.last)
+}
+
+func (t *idleTracker) watch(s *helperServer, timeout time.Duration) {
+	for {
+		wait := timeout - t.idle()
+		if wait <= 0 {
+			logger().Info("idle timeout, shutting down", "timeout", timeout)
+			ctx, cancel := context.WithTimeout(context.Background(), s.opts.shutdownTimeout())
+			defer cancel()
+			_ = s.shutdown(ctx)
+			return
+		}
+		select {
+		case <-s.stopping:
+			return
+		case <-time.After(wait):
+		}
 	}
-	unixServer = server
-	return server.Serve(l)
 }
@@ -0,0 +1,25 @@
+package sysproxy
+
+import (
+	"os"
+	"path/filepath"
+)
+
+func DefaultListenAddr() string {
+	return "/tmp/sparkle-helper.sock"
+}
+
+func DefaultTokenPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "sysproxy", "token")
+}
+
+func isAbstractSocket(_ string) bool {
+	return false
+}
+
+// sdNotify 在 macOS 上不需要，launchd 不使用就绪通知
+func sdNotify(_ string) {}
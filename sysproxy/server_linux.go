@@ -0,0 +1,47 @@
+package sysproxy
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultListenAddr 返回 $XDG_RUNTIME_DIR 下的套接字，未设置时使用临时目录
+func DefaultListenAddr() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "sysproxy.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("sysproxy-%d.sock", os.Getuid()))
+}
+
+// DefaultTokenPath 返回默认的令牌文件，与默认套接字位于同一目录
+func DefaultTokenPath() string {
+	return filepath.Join(filepath.Dir(DefaultListenAddr()), fmt.Sprintf("sysproxy-%d.token", os.Getuid()))
+}
+
+// isAbstractSocket 报告 addr 是否为以 @ 开头的抽象命名空间套接字，这类套接字没有对应的文件
+func isAbstractSocket(addr string) bool {
+	return strings.HasPrefix(addr, "@")
+}
+
+// sdNotify 向 systemd 报告服务状态，未由 systemd 以 Type=notify 启动时忽略
+func sdNotify(state string) {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return
+	}
+	if addr[0] == '@' {
+		addr = "\x00" + addr[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		logger().Debug("sd_notify failed", "error", err)
+		return
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		logger().Debug("sd_notify failed", "error", err)
+	}
+}
@@ -0,0 +1,98 @@
+package sysproxy
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+type ServerOptions struct {
+	// Listen 为 unix 套接字地址，由 systemd 或 launchd 按套接字启动时使用传入的套接字
+	Listen string
+	// IdleTimeout 大于 0 时，超过该时间没有请求且没有保持或看门狗运行则退出
+	IdleTimeout time.Duration
+
+	// SocketMode 为套接字文件的权限，为 0 时使用 0600
+	SocketMode os.FileMode
+	// SocketOwner、SocketGroup 为套接字文件的属主与属组，可以是名称或数字，为空时不修改
+	SocketOwner string
+	SocketGroup string
+
+	// Allow 限制可调用修改设置的接口的对端
+	Allow PeerPolicy
+
+	// TCP 为额外监听的 TCP 地址，请求需携带启动时生成的令牌
+	TCP string
+	// TokenFile 为写入令牌的文件，为空时使用 DefaultTokenPath()
+	TokenFile string
+	// AllowRemote 允许 TCP 监听非回环地址
+	AllowRemote bool
+
+	// ShutdownTimeout 为空闲退出或监听出错时等待进行中请求完成的时间，为 0 时为 5 秒
+	ShutdownTimeout time.Duration
+	// RestoreOnExit 为 true 时在停止时恢复启动时的代理设置
+	RestoreOnExit bool
+	// Ready 在开始接受请求后调用，参数为监听地址
+	Ready func(addrs []string)
+
+	// Metrics 为 true 时提供 Prometheus 格式的 GET /metrics
+	Metrics bool
+
+	// Backend 为 nil 时修改系统的设置
+	Backend Backend
+}
+
+func (o *ServerOptions) shutdownTimeout() time.Duration {
+	if o.ShutdownTimeout > 0 {
+		return o.ShutdownTimeout
+	}
+	return 5 * time.Second
+}
+
+// PeerCred 为 unix 套接字对端进程的身份
+type PeerCred struct {
+	PID    int    `json:"pid"`
+	UID    int    `json:"uid"`
+	GID    int    `json:"gid"`
+	Groups []int  `json:"groups,omitempty"`
+	Exe    string `json:"exe,omitempty"`
+}
+
+// PeerPolicy 限制可调用修改设置的接口的对端，满足任一条件即允许，全部为空时只允许与服务相同的用户及 root
+type PeerPolicy struct {
+	UIDs        []int
+	GIDs        []int
+	Executables []string
+}
+
+func (p *PeerPolicy) Empty() bool {
+	return len(p.UIDs) == 0 && len(p.GIDs) == 0 && len(p.Executables) == 0
+}
+
+func (p *PeerPolicy) Allows(cred *PeerCred) bool {
+	if cred == nil {
+		return false
+	}
+	if p.Empty() {
+		return cred.UID == os.Geteuid() || cred.UID == 0
+	}
+	if slices.Contains(p.UIDs, cred.UID) {
+		return true
+	}
+	if slices.Contains(p.GIDs, cred.GID) || slices.ContainsFunc(cred.Groups, func(g int) bool {
+		return slices.Contains(p.GIDs, g)
+	}) {
+		return true
+	}
+	return cred.Exe != "" && slices.ContainsFunc(p.Executables, func(exe string) bool {
+		return sameExecutable(exe, cred.Exe)
+	})
+}
+
+func sameExecutable(allowed, exe string) bool {
+	if resolved, err := filepath.EvalSymlinks(allowed); err == nil {
+		allowed = resolved
+	}
+	return filepath.Clean(allowed) == filepath.Clean(exe)
+}
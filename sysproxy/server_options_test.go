@@ -0,0 +1,55 @@
+package sysproxy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPeerPolicyAllows(t *testing.T) {
+	self := os.Geteuid()
+	other := self + 1000
+	if self == 0 {
+		other = 1000
+	}
+
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "sparkle")
+	if err := os.WriteFile(exe, nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(exe, link); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		policy PeerPolicy
+		cred   *PeerCred
+		want   bool
+	}{
+		{"no credentials", PeerPolicy{}, nil, false},
+		{"no credentials with policy", PeerPolicy{UIDs: []int{other}}, nil, false},
+		{"default same user", PeerPolicy{}, &PeerCred{UID: self}, true},
+		{"default root", PeerPolicy{}, &PeerCred{UID: 0}, true},
+		{"default other user", PeerPolicy{}, &PeerCred{UID: other, GID: other}, false},
+		{"uid allowed", PeerPolicy{UIDs: []int{other}}, &PeerCred{UID: other}, true},
+		{"uid not allowed", PeerPolicy{UIDs: []int{other + 1}}, &PeerCred{UID: other}, false},
+		{"policy replaces default", PeerPolicy{UIDs: []int{other}}, &PeerCred{UID: self}, false},
+		{"gid allowed", PeerPolicy{GIDs: []int{2000}}, &PeerCred{UID: other, GID: 2000}, true},
+		{"supplementary group allowed", PeerPolicy{GIDs: []int{2000}}, &PeerCred{UID: other, GID: other, Groups: []int{10, 2000}}, true},
+		{"gid not allowed", PeerPolicy{GIDs: []int{2000}}, &PeerCred{UID: other, GID: other, Groups: []int{10}}, false},
+		{"executable allowed", PeerPolicy{Executables: []string{exe}}, &PeerCred{UID: other, Exe: exe}, true},
+		{"executable through symlink", PeerPolicy{Executables: []string{link}}, &PeerCred{UID: other, Exe: exe}, true},
+		{"other executable", PeerPolicy{Executables: []string{exe}}, &PeerCred{UID: other, Exe: "/usr/bin/curl"}, false},
+		{"unknown executable", PeerPolicy{Executables: []string{exe}}, &PeerCred{UID: other}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Allows(tt.cred); got != tt.want {
+				t.Errorf("Allows(%+v) = %v, want %v", tt.cred, got, tt.want)
+			}
+		})
+	}
+}
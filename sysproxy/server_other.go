@@ -1,12 +1,28 @@
-//go:build !darwin
+//go:build !darwin && !linux
 
 package sysproxy
 
 import (
-	"fmt"
+	"context"
 	"runtime"
 )
 
 func Start(_ string) error {
-	return fmt.Errorf("未支持%s", runtime.GOOS)
+	return newError(CodeUnsupported, "未支持%s", runtime.GOOS)
+}
+
+func Serve(_ ServerOptions) error {
+	return newError(CodeUnsupported, "未支持%s", runtime.GOOS)
+}
+
+func Stop(_ context.Context) error {
+	return nil
+}
+
+func DefaultListenAddr() string {
+	return ""
+}
+
+func DefaultTokenPath() string {
+	return ""
 }
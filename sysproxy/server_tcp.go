@@ -0,0 +1,111 @@
+//go:build darwin || linux
+
+package sysproxy
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type tcpConnKey struct{}
+
+// viaTCP 报告请求是否来自 TCP 监听
+func viaTCP(ctx context.Context) bool {
+	return tcpRemote(ctx) != ""
+}
+
+// tcpRemote 返回 TCP 请求的对端地址，其他请求为空
+func tcpRemote(ctx context.Context) string {
+	v, _ := ctx.Value(tcpConnKey{}).(string)
+	return v
+}
+
+func checkTCPAddr(addr string, allowRemote bool) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return newError(CodeInvalidInput, "无效的监听地址：%s", addr)
+	}
+	if !allowRemote && !isLoopbackHost(host) {
+		return newError(CodeInvalidInput, "拒绝在非回环地址 %s 上监听", addr)
+	}
+	return nil
+}
+
+// listenTCP 在 addr 上监听，并生成新的令牌写入 tokenFile
+func listenTCP(addr, tokenFile string, allowRemote bool) (net.Listener, string, error) {
+	if err := checkTCPAddr(addr, allowRemote); err != nil {
+		return nil, "", err
+	}
+
+	token, err := writeToken(tokenFile)
+	if err != nil {
+		return nil, "", err
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, "", newError(CodeSystem, "监听 TCP 地址失败：%v", err)
+	}
+	logger().Info("tcp listening", "addr", l.Addr().String(), "token_file", tokenFile)
+	return l, token, nil
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "" {
+		return false
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.IsLoopback()
+	}
+	addrs, err := net.LookupHost(host)
+	if err != nil || len(addrs) == 0 {
+		return false
+	}
+	for _, a := range addrs {
+		addr, err := netip.ParseAddr(a)
+		if err != nil || !addr.IsLoopback() {
+			return false
+		}
+	}
+	return true
+}
+
+// writeToken 生成随机令牌并以 0600 权限写入 path，已存在的文件会先删除，避免沿用他人创建的文件
+func writeToken(path string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", newError(CodeSystem, "生成令牌失败：%v", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	if err := ensureDirExists(filepath.Dir(path)); err != nil {
+		return "", err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return "", newError(CodeSystem, "写入令牌文件失败：%v", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", newError(CodeSystem, "写入令牌文件失败：%v", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(token + "\n"); err != nil {
+		return "", newError(CodeSystem, "写入令牌文件失败：%v", err)
+	}
+	return token, nil
+}
+
+func validToken(r *http.Request, token string) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimSpace(got)), []byte(token)) == 1
+}
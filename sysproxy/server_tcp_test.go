@@ -0,0 +1,137 @@
+//go:build darwin || linux
+
+package sysproxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidToken(t *testing.T) {
+	const token = "0123456789abcdef"
+	tests := []struct {
+		name   string
+		header string
+		token  string
+		want   bool
+	}{
+		{"valid", "Bearer " + token, token, true},
+		{"trailing space", "Bearer " + token + " ", token, true},
+		{"missing", "", token, false},
+		{"empty", "Bearer ", token, false},
+		{"wrong", "Bearer fedcba9876543210", token, false},
+		{"prefix of token", "Bearer " + token[:8], token, false},
+		{"lowercase scheme", "bearer " + token, token, false},
+		{"basic auth", "Basic " + token, token, false},
+		{"no server token", "Bearer ", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/status", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			if got := validToken(r, tt.token); got != tt.want {
+				t.Errorf("validToken(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizeTCP(t *testing.T) {
+	const token = "0123456789abcdef"
+	handler := authorize(PeerPolicy{}, token)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer wrong", http.StatusUnauthorized},
+		{"valid token", "Bearer " + token, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// TCP 请求即使是 GET 也需要令牌
+			r := httptest.NewRequest(http.MethodGet, "/v1/status", nil)
+			r = r.WithContext(context.WithValue(r.Context(), tcpConnKey{}, "127.0.0.1:50000"))
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("WWW-Authenticate = %q, want Bearer", w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestCheckTCPAddr(t *testing.T) {
+	tests := []struct {
+		addr        string
+		allowRemote bool
+		ok          bool
+	}{
+		{"127.0.0.1:9090", false, true},
+		{"127.0.0.2:9090", false, true},
+		{"[::1]:9090", false, true},
+		{"localhost:9090", false, true},
+		{"0.0.0.0:9090", false, false},
+		{"[::]:9090", false, false},
+		{":9090", false, false},
+		{"192.168.1.5:9090", false, false},
+		{"[fd00::1]:9090", false, false},
+		{"0.0.0.0:9090", true, true},
+		{"192.168.1.5:9090", true, true},
+		{"9090", false, false},
+		{"127.0.0.1", true, false},
+	}
+	for _, tt := range tests {
+		err := checkTCPAddr(tt.addr, tt.allowRemote)
+		if (err == nil) != tt.ok {
+			t.Errorf("checkTCPAddr(%q, %v) = %v, want ok %v", tt.addr, tt.allowRemote, err, tt.ok)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("checkTCPAddr(%q, %v) = %v, want ErrInvalidInput", tt.addr, tt.allowRemote, err)
+		}
+	}
+}
+
+func TestWriteToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sysproxy", "token")
+	first, err := writeToken(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := writeToken(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second) != 64 || first == second {
+		t.Errorf("tokens %q, %q, want two different 64 character tokens", first, second)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("token file mode = %v, want 0600", info.Mode().Perm())
+	}
+	data, _ := os.ReadFile(path)
+	if strings.TrimSpace(string(data)) != second {
+		t.Errorf("token file = %q, want %q", data, second)
+	}
+}
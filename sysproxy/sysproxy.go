@@ -2,6 +2,7 @@ package sysproxy
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 )
 
@@ -18,6 +19,27 @@ type ProxyConfig struct {
 	} `json:"pac"`
 }
 
+func (c *ProxyConfig) Equal(o *ProxyConfig) bool {
+	if c == nil || o == nil {
+		return c == o
+	}
+	if c.Proxy.Enable != o.Proxy.Enable || c.Proxy.SameForAll != o.Proxy.SameForAll ||
+		c.Proxy.Bypass != o.Proxy.Bypass || c.PAC.Enable != o.PAC.Enable || c.PAC.URL != o.PAC.URL {
+		return false
+	}
+	for key, value := range c.Proxy.Servers {
+		if o.Proxy.Servers[key] != value {
+			return false
+		}
+	}
+	for key, value := range o.Proxy.Servers {
+		if c.Proxy.Servers[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
 type serverAddr struct {
 	host string
 	port string
@@ -52,3 +74,71 @@ func ParseServerString(server string) serverAddr {
 		port: server[lastIndex+1:],
 	}
 }
+
+type Mode string
+
+const (
+	ModeDirect Mode = "direct"
+	ModeProxy  Mode = "proxy"
+	ModePAC    Mode = "pac"
+)
+
+func (c *ProxyConfig) Mode() Mode {
+	switch {
+	case c == nil:
+		return ModeDirect
+	case c.PAC.Enable:
+		return ModePAC
+	case c.Proxy.Enable:
+		return ModeProxy
+	default:
+		return ModeDirect
+	}
+}
+
+func (c *ProxyConfig) Server() string {
+	for _, key := range []string{"http_server", "https_server", "socks_server"} {
+		if server := c.Proxy.Servers[key]; server != "" {
+			return server
+		}
+	}
+	return ""
+}
+
+func Diff(old, new *ProxyConfig) []string {
+	if old == nil {
+		old = &ProxyConfig{}
+	}
+	if new == nil {
+		new = &ProxyConfig{}
+	}
+
+	var diff []string
+	add := func(name string, a, b any) {
+		if a != b {
+			diff = append(diff, fmt.Sprintf("%s: %v -> %v", name, a, b))
+		}
+	}
+	add("proxy.enable", old.Proxy.Enable, new.Proxy.Enable)
+	add("proxy.same_for_all", old.Proxy.SameForAll, new.Proxy.SameForAll)
+	for _, key := range serverKeys(old, new) {
+		add("proxy.servers."+key, old.Proxy.Servers[key], new.Proxy.Servers[key])
+	}
+	add("proxy.bypass", old.Proxy.Bypass, new.Proxy.Bypass)
+	add("pac.enable", old.PAC.Enable, new.PAC.Enable)
+	add("pac.url", old.PAC.URL, new.PAC.URL)
+	return diff
+}
+
+func serverKeys(configs ...*ProxyConfig) []string {
+	var keys []string
+	for _, config := range configs {
+		for key := range config.Proxy.Servers {
+			if !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
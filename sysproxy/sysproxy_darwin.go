@@ -5,28 +5,15 @@ package sysproxy
 import (
 	"bufio"
 	"bytes"
-	"fmt"
 	"net"
 	"os/exec"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 )
 
 func DisableProxy(device string, onlyActiveDevice bool) error {
-	var (
-		services []string
-		err      error
-	)
-	if device != "" {
-		services = []string{device}
-	} else {
-		services, err = getNetworkServices(onlyActiveDevice)
-		if err != nil {
-			return err
-		}
-	}
-
 	commands := [][]string{
 		{"-setautoproxystate", "off"},
 		{"-setproxyautodiscovery", "off"},
@@ -34,31 +21,7 @@ func DisableProxy(device string, onlyActiveDevice bool) error {
 		{"-setsecurewebproxystate", "off"},
 		{"-setsocksfirewallproxystate", "off"},
 	}
-
-	errChan := make(chan error, len(services))
-	var wg sync.WaitGroup
-
-	for _, service := range services {
-		wg.Add(1)
-		go func(svc string) {
-			defer wg.Done()
-			if err := execNetworksetupConcurrent(svc, commands); err != nil {
-				errChan <- err
-			}
-		}(service)
-	}
-
-	go func() {
-		wg.Wait()
-		close(errChan)
-	}()
-
-	for err := range errChan {
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+	return execOnServices(device, onlyActiveDevice, commands)
 }
 
 func SetProxy(proxy, bypass, device string, onlyActiveDevice bool) error {
@@ -78,20 +41,7 @@ func SetProxy(proxy, bypass, device string, onlyActiveDevice bool) error {
 
 	addr := ParseServerString(proxy)
 	if addr.host == "" || addr.port == "" {
-		return fmt.Errorf("invalid proxy address: %s", proxy)
-	}
-
-	var (
-		services []string
-		err      error
-	)
-	if device != "" {
-		services = []string{device}
-	} else {
-		services, err = getNetworkServices(onlyActiveDevice)
-		if err != nil {
-			return err
-		}
+		return newError(CodeInvalidInput, "无效的代理地址：%s", proxy)
 	}
 
 	commands := [][]string{
@@ -102,31 +52,82 @@ func SetProxy(proxy, bypass, device string, onlyActiveDevice bool) error {
 		{"-setsocksfirewallproxy", addr.host, addr.port},
 		append([]string{"-setproxybypassdomains"}, strings.Split(bypass, ",")...),
 	}
+	return execOnServices(device, onlyActiveDevice, commands)
+}
 
-	errChan := make(chan error, len(services))
-	var wg sync.WaitGroup
+func setProxyServers(config *ProxyConfig, device string, onlyActiveDevice bool) error {
+	commands := [][]string{
+		{"-setautoproxystate", "off"},
+		{"-setproxyautodiscovery", "off"},
+	}
 
-	for _, service := range services {
-		wg.Add(1)
-		go func(svc string) {
-			defer wg.Done()
-			if err := execNetworksetupConcurrent(svc, commands); err != nil {
-				errChan <- err
-			}
-		}(service)
+	for _, p := range []struct{ key, set, state string }{
+		{"http_server", "-setwebproxy", "-setwebproxystate"},
+		{"https_server", "-setsecurewebproxy", "-setsecurewebproxystate"},
+		{"socks_server", "-setsocksfirewallproxy", "-setsocksfirewallproxystate"},
+	} {
+		server := config.Proxy.Servers[p.key]
+		if server == "" {
+			commands = append(commands, []string{p.state, "off"})
+			continue
+		}
+		addr := ParseServerString(server)
+		if addr.host == "" || addr.port == "" {
+			return newError(CodeInvalidInput, "无效的代理地址：%s", server)
+		}
+		commands = append(commands, []string{p.set, addr.host, addr.port})
 	}
 
-	go func() {
-		wg.Wait()
-		close(errChan)
-	}()
+	if config.Proxy.Bypass != "" {
+		commands = append(commands, append([]string{"-setproxybypassdomains"}, strings.Split(config.Proxy.Bypass, ",")...))
+	}
+	return execOnServices(device, onlyActiveDevice, commands)
+}
 
-	for err := range errChan {
-		if err != nil {
-			return err
+// restoreProxy 先写入地址、绕过列表与 PAC 地址，再设置各项的开关，
+// 两批命令分开执行，避免写入地址时自动开启的开关覆盖快照中的状态
+func restoreProxy(config *ProxyConfig, device string, onlyActiveDevice bool) error {
+	state := func(on bool) string {
+		if on {
+			return "on"
 		}
+		return "off"
 	}
-	return nil
+
+	var values, states [][]string
+	for _, p := range []struct{ key, set, state string }{
+		{"http_server", "-setwebproxy", "-setwebproxystate"},
+		{"https_server", "-setsecurewebproxy", "-setsecurewebproxystate"},
+		{"socks_server", "-setsocksfirewallproxy", "-setsocksfirewallproxystate"},
+	} {
+		server := config.Proxy.Servers[p.key]
+		if server != "" {
+			addr := ParseServerString(server)
+			if addr.host == "" || addr.port == "" {
+				return newError(CodeInvalidInput, "无效的代理地址：%s", server)
+			}
+			values = append(values, []string{p.set, addr.host, addr.port})
+		}
+		states = append(states, []string{p.state, state(config.Proxy.Enable && server != "")})
+	}
+
+	bypass := []string{"Empty"}
+	if config.Proxy.Bypass != "" {
+		bypass = strings.Split(config.Proxy.Bypass, ",")
+	}
+	values = append(values, append([]string{"-setproxybypassdomains"}, bypass...))
+	if config.PAC.URL != "" {
+		values = append(values, []string{"-setautoproxyurl", config.PAC.URL})
+	}
+	states = append(states,
+		[]string{"-setautoproxystate", state(config.PAC.Enable)},
+		[]string{"-setproxyautodiscovery", state(config.PAC.Enable)},
+	)
+
+	if err := execOnServices(device, onlyActiveDevice, values); err != nil {
+		return err
+	}
+	return execOnServices(device, onlyActiveDevice, states)
 }
 
 func SetPac(pacUrl, device string, onlyActiveDevice bool) error {
@@ -138,6 +139,18 @@ func SetPac(pacUrl, device string, onlyActiveDevice bool) error {
 		pacUrl = config.PAC.URL
 	}
 
+	commands := [][]string{
+		{"-setwebproxystate", "off"},
+		{"-setsecurewebproxystate", "off"},
+		{"-setsocksfirewallproxystate", "off"},
+		{"-setautoproxyurl", pacUrl},
+		{"-setautoproxystate", "on"},
+		{"-setproxyautodiscovery", "on"},
+	}
+	return execOnServices(device, onlyActiveDevice, commands)
+}
+
+func execOnServices(device string, onlyActiveDevice bool, commands [][]string) error {
 	var (
 		services []string
 		err      error
@@ -151,15 +164,6 @@ func SetPac(pacUrl, device string, onlyActiveDevice bool) error {
 		}
 	}
 
-	commands := [][]string{
-		{"-setwebproxystate", "off"},
-		{"-setsecurewebproxystate", "off"},
-		{"-setsocksfirewallproxystate", "off"},
-		{"-setautoproxyurl", pacUrl},
-		{"-setautoproxystate", "on"},
-		{"-setproxyautodiscovery", "on"},
-	}
-
 	errChan := make(chan error, len(services))
 	var wg sync.WaitGroup
 
@@ -204,7 +208,7 @@ func QueryProxySettings(device string, onlyActiveDevice bool) (*ProxyConfig, err
 	config := &ProxyConfig{}
 	config.Proxy.Servers = make(map[string]string)
 
-	output, err := exec.Command("networksetup", "-getautoproxyurl", service).Output()
+	output, err := commandOutput(exec.Command("networksetup", "-getautoproxyurl", service))
 	if err == nil && strings.Contains(string(output), "Enabled: Yes") {
 		config.PAC.Enable = true
 		lines := strings.SplitSeq(string(output), "\n")
@@ -237,7 +241,7 @@ func QueryProxySettings(device string, onlyActiveDevice bool) (*ProxyConfig, err
 		}
 	}
 
-	if output, err := exec.Command("networksetup", "-getproxybypassdomains", service).Output(); err == nil {
+	if output, err := commandOutput(exec.Command("networksetup", "-getproxybypassdomains", service)); err == nil {
 		bypass := strings.ReplaceAll(strings.TrimSpace(string(output)), "\n", ",")
 		if bypass != "" {
 			config.Proxy.Bypass = bypass
@@ -253,19 +257,19 @@ func getNetworkServices(onlyActiveDevice bool) ([]string, error) {
 		err    error
 	)
 	if onlyActiveDevice {
-		ifaces, err = net.Interfaces()
+		ifaces, err = activeInterfaces()
 		if err != nil {
-			return nil, fmt.Errorf("无法获取网络接口: %w", err)
+			return nil, err
 		}
 	}
 
 	cmd := exec.Command("networksetup", "-listnetworkserviceorder")
-	output, err := cmd.Output()
+	output, err := commandOutput(cmd)
 	if err != nil {
-		return nil, fmt.Errorf("无法执行 networksetup 命令: %w", err)
+		return nil, newError(CodeSystem, "无法执行 networksetup 命令：%v", err)
 	}
 	if len(output) == 0 {
-		return nil, fmt.Errorf("networksetup 命令没有输出")
+		return nil, newError(CodeSystem, "networksetup 命令没有输出")
 	}
 
 	ordinalRegex := regexp.MustCompile(`^\(\d+\)\s*(.+)$`)
@@ -287,22 +291,7 @@ func getNetworkServices(onlyActiveDevice bool) ([]string, error) {
 			}
 
 			if onlyActiveDevice {
-				var matchIface *net.Interface
-				for _, i := range ifaces {
-					if i.Name != device {
-						continue
-					}
-					if i.Flags&net.FlagUp == 0 || i.Flags&net.FlagRunning == 0 {
-						continue
-					}
-					addrs, _ := i.Addrs()
-					if len(addrs) == 0 {
-						continue
-					}
-					matchIface = &i
-					break
-				}
-				if matchIface != nil {
+				if slices.ContainsFunc(ifaces, func(i net.Interface) bool { return i.Name == device }) {
 					services = append(services, service)
 				}
 			} else {
@@ -312,11 +301,11 @@ func getNetworkServices(onlyActiveDevice bool) ([]string, error) {
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("扫描输出时出错: %w", err)
+		return nil, newError(CodeSystem, "扫描输出时出错：%v", err)
 	}
 
 	if len(services) == 0 {
-		return nil, fmt.Errorf("未找到活跃的网络服务")
+		return nil, newError(CodeSystem, "未找到活跃的网络服务")
 	}
 
 	return services, nil
@@ -330,8 +319,8 @@ func execNetworksetupConcurrent(service string, commands [][]string) error {
 		wg.Add(1)
 		go func(args []string) {
 			defer wg.Done()
-			if err := exec.Command("networksetup", args...).Run(); err != nil {
-				errChan <- fmt.Errorf("执行 networksetup %v 时出错，服务 %s: %w", args, service, err)
+			if err := runCommand(exec.Command("networksetup", args...)); err != nil {
+				errChan <- newError(CodeSystem, "执行 networksetup %v 时出错，服务 %s：%v", args, service, err)
 			}
 		}(append([]string{cmd[0]}, append([]string{service}, cmd[1:]...)...))
 	}
@@ -351,7 +340,7 @@ func execNetworksetupConcurrent(service string, commands [][]string) error {
 }
 
 func parseProxy(cmd *exec.Cmd) (enabled bool, host, port string) {
-	if output, err := cmd.Output(); err == nil {
+	if output, err := commandOutput(cmd); err == nil {
 		for line := range strings.SplitSeq(strings.TrimSpace(string(output)), "\n") {
 			switch {
 			case strings.HasPrefix(line, "Enabled: Yes"):
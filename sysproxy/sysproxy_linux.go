@@ -25,7 +25,7 @@ func (e *Environment) Init() error {
 
 	desktop := os.Getenv("XDG_CURRENT_DESKTOP")
 	if desktop == "" {
-		return fmt.Errorf("XDG_CURRENT_DESKTOP environment variable not set")
+		return newError(CodeUnsupported, "未设置 XDG_CURRENT_DESKTOP 环境变量")
 	}
 
 	e.desktop = desktop
@@ -50,7 +50,7 @@ func DisableProxy(_ string, _ bool) error {
 	case e.isGnome:
 		return clearGnomeProxy()
 	default:
-		return fmt.Errorf("不支持的桌面：%s", e.desktop)
+		return newError(CodeUnsupported, "不支持的桌面：%s", e.desktop)
 	}
 }
 
@@ -73,15 +73,23 @@ func SetProxy(proxy, bypass, _ string, _ bool) error {
 		return err
 	}
 
-	config := &ProxyConfig{}
-	config.Proxy.Enable = true
-	config.Proxy.SameForAll = true
-	config.Proxy.Servers = map[string]string{
-		"http_server":  proxy,
-		"https_server": proxy,
-		"socks_server": proxy,
+	config := NewProxyConfig(proxy, bypass)
+
+	switch {
+	case e.isKde:
+		return setKDEProxy(config, e.isKde6)
+	case e.isGnome:
+		return setGnomeProxy(config)
+	default:
+		return newError(CodeUnsupported, "不支持的桌面：%s", e.desktop)
+	}
+}
+
+func setProxyServers(config *ProxyConfig, _ string, _ bool) error {
+	e := &Environment{}
+	if err := e.Init(); err != nil {
+		return err
 	}
-	config.Proxy.Bypass = bypass
 
 	switch {
 	case e.isKde:
@@ -89,7 +97,23 @@ func SetProxy(proxy, bypass, _ string, _ bool) error {
 	case e.isGnome:
 		return setGnomeProxy(config)
 	default:
-		return fmt.Errorf("不支持的桌面：%s", e.desktop)
+		return newError(CodeUnsupported, "不支持的桌面：%s", e.desktop)
+	}
+}
+
+func restoreProxy(config *ProxyConfig, _ string, _ bool) error {
+	e := &Environment{}
+	if err := e.Init(); err != nil {
+		return err
+	}
+
+	switch {
+	case e.isKde:
+		return restoreKDEProxy(config, e.isKde6)
+	case e.isGnome:
+		return restoreGnomeProxy(config)
+	default:
+		return newError(CodeUnsupported, "不支持的桌面：%s", e.desktop)
 	}
 }
 
@@ -107,9 +131,7 @@ func SetPac(pacUrl, _ string, _ bool) error {
 		pacUrl = currentConfig.PAC.URL
 	}
 
-	config := &ProxyConfig{}
-	config.PAC.Enable = true
-	config.PAC.URL = pacUrl
+	config := NewPacConfig(pacUrl)
 
 	switch {
 	case e.isKde:
@@ -117,7 +139,7 @@ func SetPac(pacUrl, _ string, _ bool) error {
 	case e.isGnome:
 		return setGnomePac(config)
 	default:
-		return fmt.Errorf("不支持的桌面：%s", e.desktop)
+		return newError(CodeUnsupported, "不支持的桌面：%s", e.desktop)
 	}
 }
 
@@ -133,14 +155,14 @@ func QueryProxySettings(_ string, _ bool) (*ProxyConfig, error) {
 	case e.isGnome:
 		return queryGnomeSettings()
 	default:
-		return nil, fmt.Errorf("不支持的桌面：%s", e.desktop)
+		return nil, newError(CodeUnsupported, "不支持的桌面：%s", e.desktop)
 	}
 }
 
 func execAsCurrentUser(name string, arg ...string) *exec.Cmd {
 	cmd := exec.Command(name, arg...)
 	if os.Geteuid() == 0 {
-		fmt.Println(os.Getuid(), os.Getgid())
+		logger().Debug("exec as current user", "cmd", name, "uid", os.Getuid(), "gid", os.Getgid())
 		cmd.SysProcAttr = &syscall.SysProcAttr{
 			Credential: &syscall.Credential{
 				Uid: uint32(os.Getuid()),
@@ -171,9 +193,9 @@ func queryGnomeSettings() (*ProxyConfig, error) {
 	}
 
 	for _, key := range keys {
-		output, err := execAsCurrentUser("gsettings", append([]string{"get"}, strings.Split(key.path, " ")...)...).Output()
+		output, err := commandOutput(execAsCurrentUser("gsettings", append([]string{"get"}, strings.Split(key.path, " ")...)...))
 		if err != nil {
-			return nil, fmt.Errorf("无法读取 %s 的 GNOME 配置：%v", key.name, err)
+			return nil, newError(CodeSystem, "无法读取 %s 的 GNOME 配置：%v", key.name, err)
 		}
 		settings[key.name] = string(output)
 	}
@@ -216,7 +238,11 @@ func setGnomeProxy(config *ProxyConfig) error {
 	}
 
 	for proxyType, addr := range proxyTypes {
-		fmt.Println(proxyType, addr)
+		logger().Debug("set gnome proxy", "type", proxyType, "host", addr.host, "port", addr.port)
+		if addr.host == "" && !config.Proxy.SameForAll {
+			// 分协议设置时清除未指定的协议，避免沿用旧的地址
+			addr.host, addr.port = "''", "0"
+		}
 		if addr.host != "" {
 			if err := execGsettings(fmt.Sprintf("org.gnome.system.proxy.%s", proxyType), "host", addr.host); err != nil {
 				return err
@@ -244,12 +270,55 @@ func setGnomePac(config *ProxyConfig) error {
 	return execGsettings("org.gnome.system.proxy", "autoconfig-url", config.PAC.URL)
 }
 
+// restoreGnomeProxy 写入所有代理键，最后切换模式，未设置的地址与 PAC 地址被清空
+func restoreGnomeProxy(config *ProxyConfig) error {
+	for _, proxyType := range []string{"http", "https", "ftp", "socks"} {
+		addr := ParseServerString(config.Proxy.Servers[proxyType+"_server"])
+		if addr.host == "" {
+			addr.host, addr.port = "''", "0"
+		}
+		if err := execGsettings(fmt.Sprintf("org.gnome.system.proxy.%s", proxyType), "host", addr.host); err != nil {
+			return err
+		}
+		if err := execGsettings(fmt.Sprintf("org.gnome.system.proxy.%s", proxyType), "port", addr.port); err != nil {
+			return err
+		}
+	}
+
+	bypassList := "[]"
+	if config.Proxy.Bypass != "" {
+		bypassList = fmt.Sprintf("['%s']", strings.Join(strings.Split(config.Proxy.Bypass, ","), "','"))
+	}
+	pacUrl := config.PAC.URL
+	if pacUrl == "" {
+		pacUrl = "''"
+	}
+	mode := "none"
+	switch config.Mode() {
+	case ModeProxy:
+		mode = "manual"
+	case ModePAC:
+		mode = "auto"
+	}
+	for _, kv := range [][2]string{
+		{"ignore-hosts", bypassList},
+		{"use-same-proxy", fmt.Sprintf("%v", config.Proxy.SameForAll)},
+		{"autoconfig-url", pacUrl},
+		{"mode", mode},
+	} {
+		if err := execGsettings("org.gnome.system.proxy", kv[0], kv[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func clearGnomeProxy() error {
 	return execGsettings("org.gnome.system.proxy", "mode", "none")
 }
 
 func execGsettings(schema, key, value string) error {
-	return execAsCurrentUser("gsettings", "set", schema, key, value).Run()
+	return runCommand(execAsCurrentUser("gsettings", "set", schema, key, value))
 }
 
 func queryKDESettings(isKde6 bool) (*ProxyConfig, error) {
@@ -275,9 +344,9 @@ func queryKDESettings(isKde6 bool) (*ProxyConfig, error) {
 	}
 
 	for key := range keys {
-		output, err := execAsCurrentUser(cmd, "--file", "kioslaverc", "--group", group, "--key", key).Output()
+		output, err := commandOutput(execAsCurrentUser(cmd, "--file", "kioslaverc", "--group", group, "--key", key))
 		if err != nil {
-			return nil, fmt.Errorf("无法读取 %s 的 KDE 配置：%v", key, err)
+			return nil, newError(CodeSystem, "无法读取 %s 的 KDE 配置：%v", key, err)
 		}
 		keys[key] = cleanOutput(string(output))
 	}
@@ -376,7 +445,47 @@ func clearKDEProxy(isKde6 bool) error {
 	return execKDEConfig(cmd, "ProxyType", "0", group)
 }
 
+// restoreKDEProxy 写入所有代理键，最后写入 ProxyType
+func restoreKDEProxy(config *ProxyConfig, isKde6 bool) error {
+	cmd := "kwriteconfig5"
+	if isKde6 {
+		cmd = "kwriteconfig6"
+	}
+
+	group := "Proxy Settings"
+	if !isKde6 {
+		group = "Proxy"
+	}
+
+	proxyType := "0"
+	switch config.Mode() {
+	case ModeProxy:
+		proxyType = "1"
+	case ModePAC:
+		proxyType = "2"
+	}
+	sameProxy := "false"
+	if config.Proxy.SameForAll {
+		sameProxy = "true"
+	}
+	for _, kv := range [][2]string{
+		{"httpProxy", config.Proxy.Servers["http_server"]},
+		{"httpsProxy", config.Proxy.Servers["https_server"]},
+		{"socksProxy", config.Proxy.Servers["socks_server"]},
+		{"ftpProxy", config.Proxy.Servers["ftp_server"]},
+		{"NoProxyFor", config.Proxy.Bypass},
+		{"Proxy Config Script", config.PAC.URL},
+		{"UseSameProxy", sameProxy},
+		{"ProxyType", proxyType},
+	} {
+		if err := execKDEConfig(cmd, kv[0], kv[1], group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func execKDEConfig(cmd, key, value, group string) error {
 	args := []string{"--file", "kioslaverc", "--group", group, "--key", key, value}
-	return execAsCurrentUser(cmd, args...).Run()
+	return runCommand(execAsCurrentUser(cmd, args...))
 }
@@ -3,8 +3,9 @@
 package sysproxy
 
 import (
-	"fmt"
+	"strings"
 	"syscall"
+	"time"
 	"unsafe"
 
 	"golang.org/x/sys/windows/registry"
@@ -49,7 +50,7 @@ type (
 func refreshAndApplySettings(options []InternetPerConnOption) error {
 	connectionNames, err := enumAllConnectionNames()
 	if err != nil {
-		return fmt.Errorf("获取连接名失败：%v", err)
+		return newError(CodeSystem, "获取连接名失败：%v", err)
 	}
 
 	connectionNames = append(connectionNames, "")
@@ -71,20 +72,39 @@ func refreshAndApplySettings(options []InternetPerConnOption) error {
 			pOptions:      &options[0],
 		}
 
-		if ret, _, err := procInternetSetOptionW.Call(
+		if ret, err := callWinINet(procInternetSetOptionW, []string{"INTERNET_OPTION_PER_CONNECTION_OPTION", name},
 			0,
 			INTERNET_OPTION_PER_CONNECTION_OPTION,
 			uintptr(unsafe.Pointer(&list)),
 			unsafe.Sizeof(list)); ret == 0 {
-			return fmt.Errorf("设置 %s 连接失败：%v", name, err)
+			return newError(CodeSystem, "设置 %s 连接失败：%v", name, err)
 		}
 	}
 
-	procInternetSetOptionW.Call(0, INTERNET_OPTION_PROXY_SETTINGS_CHANGED, 0, 0)
-	procInternetSetOptionW.Call(0, INTERNET_OPTION_REFRESH, 0, 0)
+	callWinINet(procInternetSetOptionW, []string{"INTERNET_OPTION_PROXY_SETTINGS_CHANGED"}, 0, INTERNET_OPTION_PROXY_SETTINGS_CHANGED, 0, 0)
+	callWinINet(procInternetSetOptionW, []string{"INTERNET_OPTION_REFRESH"}, 0, INTERNET_OPTION_REFRESH, 0, 0)
 	return nil
 }
 
+// callWinINet 调用 WinINet 函数并记录跟踪，traceArgs 为跟踪中显示的参数
+func callWinINet(proc *syscall.LazyProc, traceArgs []string, args ...uintptr) (uintptr, error) {
+	start := time.Now()
+	ret, _, err := proc.Call(args...)
+	event := TraceEvent{
+		Kind:     TraceWinINet,
+		Name:     proc.Name,
+		Args:     traceArgs,
+		Start:    start,
+		Duration: time.Since(start),
+		ExitCode: int(ret),
+	}
+	if ret == 0 {
+		event.Error = err.Error()
+	}
+	traceCall(event)
+	return ret, err
+}
+
 func DisableProxy(_ string, _ bool) error {
 	return refreshAndApplySettings([]InternetPerConnOption{{
 		dwOption: INTERNET_PER_CONN_FLAGS,
@@ -122,6 +142,58 @@ func SetProxy(proxy, bypass, _ string, _ bool) error {
 	})
 }
 
+func setProxyServers(config *ProxyConfig, device string, onlyActiveDevice bool) error {
+	return SetProxy(serverList(config), config.Proxy.Bypass, device, onlyActiveDevice)
+}
+
+// serverList 将分协议的地址转换为 http=host:port;https=host:port 格式
+func serverList(config *ProxyConfig) string {
+	var servers []string
+	for _, p := range []struct{ key, protocol string }{
+		{"http_server", "http"},
+		{"https_server", "https"},
+		{"ftp_server", "ftp"},
+		{"socks_server", "socks"},
+	} {
+		if server := config.Proxy.Servers[p.key]; server != "" {
+			servers = append(servers, p.protocol+"="+server)
+		}
+	}
+	return strings.Join(servers, ";")
+}
+
+// restoreProxy 一次写入标志、代理地址、绕过列表与 PAC 地址
+func restoreProxy(config *ProxyConfig, _ string, _ bool) error {
+	flags := uintptr(PROXY_TYPE_DIRECT)
+	if config.Proxy.Enable {
+		flags |= PROXY_TYPE_PROXY
+	}
+	if config.PAC.Enable {
+		flags |= PROXY_TYPE_AUTO_PROXY_URL
+	}
+	server := config.Server()
+	if !config.Proxy.SameForAll {
+		server = serverList(config)
+	}
+
+	options := []InternetPerConnOption{{dwOption: INTERNET_PER_CONN_FLAGS, dwValue: flags}}
+	for _, o := range []struct {
+		option uint32
+		value  string
+	}{
+		{INTERNET_PER_CONN_PROXY_SERVER, server},
+		{INTERNET_PER_CONN_PROXY_BYPASS, config.Proxy.Bypass},
+		{INTERNET_PER_CONN_AUTOCONFIG_URL, config.PAC.URL},
+	} {
+		ptr, err := syscall.UTF16PtrFromString(o.value)
+		if err != nil {
+			return err
+		}
+		options = append(options, InternetPerConnOption{dwOption: o.option, dwValue: uintptr(unsafe.Pointer(ptr))})
+	}
+	return refreshAndApplySettings(options)
+}
+
 func SetPac(pacUrl, _ string, _ bool) error {
 	if pacUrl == "" {
 		return refreshAndApplySettings([]InternetPerConnOption{
@@ -153,20 +225,27 @@ func QueryProxySettings(_ string, _ bool) (*ProxyConfig, error) {
 		pOptions:      &options[0],
 	}
 
-	if ret, _, err := procInternetQueryOptionW.Call(
+	if ret, err := callWinINet(procInternetQueryOptionW, []string{"INTERNET_OPTION_PER_CONNECTION_OPTION"},
 		0,
 		INTERNET_OPTION_PER_CONNECTION_OPTION,
 		uintptr(unsafe.Pointer(&list)),
 		uintptr(unsafe.Pointer(&list.dwSize))); ret == 0 {
-		return nil, fmt.Errorf("查询失败：%v", err)
+		return nil, newError(CodeSystem, "查询失败：%v", err)
 	}
 
 	flags := uint32(options[0].dwValue)
 	config := &ProxyConfig{}
 
 	config.Proxy.Enable = (flags & PROXY_TYPE_PROXY) != 0
+	server := getString(options[1].dwValue)
 	config.Proxy.Servers = map[string]string{
-		"http_server": getString(options[1].dwValue),
+		"http_server": server,
+	}
+	// 不带协议前缀的地址对所有协议生效
+	config.Proxy.SameForAll = true
+	if strings.Contains(server, "=") {
+		config.Proxy.SameForAll = false
+		config.Proxy.Servers = parseServerList(server)
 	}
 	config.Proxy.Bypass = getString(options[2].dwValue)
 	config.PAC.Enable = (flags & PROXY_TYPE_AUTO_PROXY_URL) != 0
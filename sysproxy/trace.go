@@ -0,0 +1,77 @@
+package sysproxy
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	TraceExec    = "exec"
+	TraceWinINet = "wininet"
+)
+
+// TraceEvent 记录一次外部命令或系统接口调用
+type TraceEvent struct {
+	Kind     string        `json:"kind"`
+	Name     string        `json:"name"`
+	Args     []string      `json:"args,omitempty"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	// ExitCode 为命令的退出码或系统接口的返回值
+	ExitCode int    `json:"exit_code"`
+	Stderr   string `json:"stderr,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+var traceHook atomic.Pointer[func(TraceEvent)]
+
+// SetTraceHook 设置调用跟踪回调，为 nil 时关闭跟踪，回调可能被并发调用
+func SetTraceHook(fn func(TraceEvent)) {
+	if fn == nil {
+		traceHook.Store(nil)
+		return
+	}
+	traceHook.Store(&fn)
+}
+
+func traceCall(event TraceEvent) {
+	if fn := traceHook.Load(); fn != nil {
+		(*fn)(event)
+	}
+}
+
+func runCommand(cmd *exec.Cmd) error {
+	_, err := commandOutput(cmd)
+	return err
+}
+
+// commandOutput 执行命令并返回标准输出，同时记录跟踪
+func commandOutput(cmd *exec.Cmd) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	event := TraceEvent{
+		Kind:     TraceExec,
+		Name:     cmd.Args[0],
+		Args:     cmd.Args[1:],
+		Start:    start,
+		Duration: time.Since(start),
+		ExitCode: -1,
+		Stderr:   strings.TrimSpace(stderr.String()),
+	}
+	if cmd.ProcessState != nil {
+		event.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	if err != nil {
+		event.Error = err.Error()
+		logger().Debug("command failed", "cmd", cmd.Args, "error", err, "stderr", event.Stderr)
+	}
+	traceCall(event)
+	return stdout.Bytes(), err
+}
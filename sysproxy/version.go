@@ -0,0 +1,4 @@
+package sysproxy
+
+// Version 为程序版本，发布时通过 -ldflags "-X github.com/xishang0128/sysproxy-go/sysproxy.Version=v1.2.3" 设置
+var Version = "dev"
@@ -0,0 +1,110 @@
+package sysproxy
+
+import (
+	"context"
+	"time"
+)
+
+type Event struct {
+	Time time.Time    `json:"time"`
+	Old  *ProxyConfig `json:"old"`
+	New  *ProxyConfig `json:"new"`
+}
+
+// DefaultWatchInterval 为没有变更通知时默认的轮询间隔
+const DefaultWatchInterval = 2 * time.Second
+
+// watchDebounce 用于合并一次设置产生的多条变更通知
+const watchDebounce = 300 * time.Millisecond
+
+type WatchOptions struct {
+	Device           string
+	OnlyActiveDevice bool
+	// Interval 为没有变更通知时的轮询间隔，为 0 时使用 DefaultWatchInterval
+	Interval time.Duration
+}
+
+func Watch(ctx context.Context) <-chan Event {
+	return WatchWith(ctx, WatchOptions{})
+}
+
+func WatchDevice(ctx context.Context, device string, onlyActiveDevice bool) <-chan Event {
+	return WatchWith(ctx, WatchOptions{Device: device, OnlyActiveDevice: onlyActiveDevice})
+}
+
+func WatchWith(ctx context.Context, opts WatchOptions) <-chan Event {
+	return watchDevice(ctx, SystemBackend{}, opts)
+}
+
+// watchDevice 在系统的设置来源变化或轮询时通过 b 查询设置
+func watchDevice(ctx context.Context, b Backend, opts WatchOptions) <-chan Event {
+	device, onlyActiveDevice := opts.Device, opts.OnlyActiveDevice
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+
+		trigger := make(chan struct{}, 1)
+		var tick <-chan time.Time
+		if !watchSources(ctx, trigger) {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		old, _ := b.Query(device, onlyActiveDevice)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick:
+			case <-trigger:
+				if !debounce(ctx, trigger) {
+					return
+				}
+			}
+
+			current, err := b.Query(device, onlyActiveDevice)
+			if err != nil {
+				continue
+			}
+			if old == nil || old.Equal(current) {
+				old = current
+				continue
+			}
+
+			select {
+			case events <- Event{Time: time.Now(), Old: old, New: current}:
+			case <-ctx.Done():
+				return
+			}
+			old = current
+		}
+	}()
+	return events
+}
+
+func debounce(ctx context.Context, trigger <-chan struct{}) bool {
+	timer := time.NewTimer(watchDebounce)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-trigger:
+			timer.Reset(watchDebounce)
+		case <-timer.C:
+			return true
+		}
+	}
+}
+
+func notify(trigger chan<- struct{}) {
+	select {
+	case trigger <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,154 @@
+//go:build linux
+
+package sysproxy
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+var gnomeProxySchemas = []string{
+	"org.gnome.system.proxy",
+	"org.gnome.system.proxy.http",
+	"org.gnome.system.proxy.https",
+	"org.gnome.system.proxy.ftp",
+	"org.gnome.system.proxy.socks",
+}
+
+func watchSources(ctx context.Context, trigger chan<- struct{}) bool {
+	covered := false
+
+	e := &Environment{}
+	if err := e.Init(); err == nil {
+		switch {
+		case e.isKde:
+			covered = watchFiles(ctx, trigger, kdeConfigPath()) == nil
+		case e.isGnome:
+			covered = watchGsettings(ctx, trigger) == nil
+		}
+	}
+
+	_ = watchFiles(ctx, trigger, environmentFiles()...)
+	return covered
+}
+
+func kdeConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "kioslaverc")
+}
+
+func environmentFiles() []string {
+	files := []string{"/etc/environment", "/etc/profile.d"}
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, ".pam_environment"))
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		files = append(files, filepath.Join(dir, "environment.d"))
+	}
+	return files
+}
+
+func watchGsettings(ctx context.Context, trigger chan<- struct{}) error {
+	for _, schema := range gnomeProxySchemas {
+		cmd := execAsCurrentUser("gsettings", "monitor", schema)
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return err
+		}
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+
+		go func() {
+			<-ctx.Done()
+			_ = cmd.Process.Kill()
+		}()
+		go func() {
+			scanner := bufio.NewScanner(stdout)
+			for scanner.Scan() {
+				notify(trigger)
+			}
+			_ = cmd.Wait()
+		}()
+	}
+	return nil
+}
+
+// watchFiles 监听文件所在目录，以便捕获 kwriteconfig 等工具通过重命名写入的变更
+func watchFiles(ctx context.Context, trigger chan<- struct{}, paths ...string) error {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return err
+	}
+	file := os.NewFile(uintptr(fd), "inotify")
+
+	const mask = unix.IN_CLOSE_WRITE | unix.IN_MOVED_TO | unix.IN_CREATE | unix.IN_DELETE
+	names := map[int]map[string]bool{}
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			if wd, err := unix.InotifyAddWatch(fd, path, mask); err == nil {
+				names[wd] = nil
+			}
+			continue
+		}
+		wd, err := unix.InotifyAddWatch(fd, filepath.Dir(path), mask)
+		if err != nil {
+			continue
+		}
+		if _, ok := names[wd]; !ok {
+			names[wd] = map[string]bool{}
+		}
+		if names[wd] != nil {
+			names[wd][filepath.Base(path)] = true
+		}
+	}
+	if len(names) == 0 {
+		_ = file.Close()
+		return os.ErrNotExist
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = file.Close()
+	}()
+	go func() {
+		buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+		for {
+			n, err := file.Read(buf)
+			if err != nil {
+				return
+			}
+			for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+				event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+				nameBytes := buf[offset+unix.SizeofInotifyEvent : offset+unix.SizeofInotifyEvent+int(event.Len)]
+				offset += unix.SizeofInotifyEvent + int(event.Len)
+
+				filter := names[int(event.Wd)]
+				if filter == nil || filter[cString(nameBytes)] {
+					notify(trigger)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func cString(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package sysproxy
+
+import "context"
+
+func watchSources(_ context.Context, _ chan<- struct{}) bool {
+	return false
+}
@@ -0,0 +1,255 @@
+package sysproxy
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+type WatchdogState string
+
+const (
+	WatchdogHealthy WatchdogState = "healthy"
+	WatchdogFailing WatchdogState = "failing"
+	WatchdogTripped WatchdogState = "tripped"
+)
+
+type WatchdogStatus struct {
+	State     WatchdogState `json:"state"`
+	Endpoint  string        `json:"endpoint"`
+	Failures  int           `json:"failures"`
+	LastCheck time.Time     `json:"last_check"`
+	LastError string        `json:"last_error,omitempty"`
+}
+
+// Watchdog 定期探测代理端点，连续失败后切换到直连或备用设置，端点恢复后重新应用代理
+type Watchdog struct {
+	// Proxy 在端点恢复时通过 Restore 完整写入，可以是 QueryProxySettings 返回的设置
+	Proxy            *ProxyConfig
+	Fallback         *ProxyConfig
+	Device           string
+	OnlyActiveDevice bool
+
+	// Endpoint 为空时使用 Proxy 中的代理地址
+	Endpoint string
+	// Probe 可选 tcp、http、socks5
+	Probe string
+	// ProbeFunc 非 nil 时代替 Probe 探测端点
+	ProbeFunc func(ctx context.Context, endpoint string, timeout time.Duration) error
+
+	Interval time.Duration
+	Timeout  time.Duration
+	Failures int
+
+	OnStateChange func(WatchdogStatus)
+	// Backend 为 nil 时修改系统的设置，服务通过它把切换提交到修改队列
+	Backend Backend
+
+	mu     sync.Mutex
+	status WatchdogStatus
+}
+
+func (w *Watchdog) Run(ctx context.Context) error {
+	endpoint, probe, err := w.check()
+	if err != nil {
+		return err
+	}
+
+	interval := w.Interval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	timeout := w.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	threshold := w.Failures
+	if threshold <= 0 {
+		threshold = 3
+	}
+	b := orSystem(w.Backend)
+	fallback := w.Fallback
+	if fallback == nil {
+		fallback = &ProxyConfig{}
+	}
+
+	w.mu.Lock()
+	w.status = WatchdogStatus{State: WatchdogHealthy, Endpoint: endpoint}
+	w.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := probe(ctx, endpoint, timeout)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		w.mu.Lock()
+		status := w.status
+		w.mu.Unlock()
+
+		previous := status.State
+		status.LastCheck = time.Now()
+		status.LastError = ""
+		if err != nil {
+			status.Failures++
+			status.LastError = err.Error()
+			switch {
+			case status.State == WatchdogTripped:
+			case status.Failures >= threshold:
+				applyErr := b.Apply(fallback, w.Device, w.OnlyActiveDevice)
+				if applyErr != nil {
+					status.LastError = applyErr.Error()
+					status.State = WatchdogFailing
+				} else {
+					status.State = WatchdogTripped
+				}
+			default:
+				status.State = WatchdogFailing
+			}
+		} else {
+			status.Failures = 0
+			if status.State == WatchdogTripped {
+				applyErr := b.Restore(w.Proxy, w.Device, w.OnlyActiveDevice)
+				if applyErr != nil {
+					status.LastError = applyErr.Error()
+				} else {
+					status.State = WatchdogHealthy
+				}
+			} else {
+				status.State = WatchdogHealthy
+			}
+		}
+
+		w.mu.Lock()
+		w.status = status
+		w.mu.Unlock()
+		if status.State != previous && w.OnStateChange != nil {
+			w.OnStateChange(status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Validate 检查探测地址与探测方式，应在应用 Proxy 之前调用，避免写入无法探测的设置
+func (w *Watchdog) Validate() error {
+	_, _, err := w.check()
+	return err
+}
+
+// check 返回探测的地址与探测函数，地址或探测方式无效时返回错误
+func (w *Watchdog) check() (string, probeFn, error) {
+	endpoint := w.Endpoint
+	if endpoint == "" {
+		endpoint = stripScheme(w.Proxy.Server())
+	}
+	if addr := ParseServerString(endpoint); addr.host == "" || addr.port == "" {
+		return "", nil, newError(CodeInvalidInput, "无效的代理地址：%s", endpoint)
+	}
+	if w.ProbeFunc != nil {
+		return endpoint, w.ProbeFunc, nil
+	}
+	probe, err := probeFunc(w.Probe)
+	if err != nil {
+		return "", nil, err
+	}
+	return endpoint, probe, nil
+}
+
+func (w *Watchdog) Status() WatchdogStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+type probeFn func(ctx context.Context, endpoint string, timeout time.Duration) error
+
+func probeFunc(name string) (probeFn, error) {
+	switch name {
+	case "", "tcp":
+		return probeTCP, nil
+	case "http":
+		return probeHTTP, nil
+	case "socks5":
+		return probeSocks5, nil
+	default:
+		return nil, newError(CodeInvalidInput, "未知的探测方式：%s", name)
+	}
+}
+
+func dialProbe(ctx context.Context, endpoint string, timeout time.Duration) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", endpoint)
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	return conn, nil
+}
+
+func probeTCP(ctx context.Context, endpoint string, timeout time.Duration) error {
+	conn, err := dialProbe(ctx, endpoint, timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// probeHTTP 发送一个无需代理向上游连接的请求，只要收到 HTTP 响应即视为存活
+func probeHTTP(ctx context.Context, endpoint string, timeout time.Duration) error {
+	conn, err := dialProbe(ctx, endpoint, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := io.WriteString(conn, "OPTIONS * HTTP/1.0\r\n\r\n"); err != nil {
+		return err
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "HTTP/") {
+		return newError(CodeUnavailable, "无效的 HTTP 响应：%q", strings.TrimSpace(line))
+	}
+	return nil
+}
+
+func probeSocks5(ctx context.Context, endpoint string, timeout time.Duration) error {
+	conn, err := dialProbe(ctx, endpoint, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte{0x05, 0x01, 0x00}); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	// 0xFF 表示服务端不接受无需认证的方式
+	if reply[0] != 0x05 || reply[1] == 0xFF {
+		return newError(CodeUnavailable, "无效的 SOCKS5 响应：%x", reply)
+	}
+	return nil
+}
+
+func stripScheme(server string) string {
+	if i := strings.Index(server, "://"); i != -1 {
+		return server[i+3:]
+	}
+	return server
+}
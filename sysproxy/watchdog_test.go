@@ -0,0 +1,207 @@
+package sysproxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeProbe 按 healthy 返回探测结果
+type fakeProbe struct {
+	healthy atomic.Bool
+}
+
+func (p *fakeProbe) probe(_ context.Context, _ string, _ time.Duration) error {
+	if p.healthy.Load() {
+		return nil
+	}
+	return errors.New("connection refused")
+}
+
+// launchWatchdog 在后台运行 w，返回状态变化
+func launchWatchdog(t *testing.T, w *Watchdog) <-chan WatchdogStatus {
+	states := make(chan WatchdogStatus, 16)
+	w.Interval = 5 * time.Millisecond
+	w.OnStateChange = func(s WatchdogStatus) { states <- s }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	})
+	return states
+}
+
+func nextState(t *testing.T, states <-chan WatchdogStatus) WatchdogStatus {
+	t.Helper()
+	select {
+	case s := <-states:
+		return s
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for watchdog state change")
+		return WatchdogStatus{}
+	}
+}
+
+func TestWatchdogTripAndRestore(t *testing.T) {
+	tests := []struct {
+		name     string
+		fallback *ProxyConfig
+		want     *ProxyConfig
+	}{
+		{"direct", nil, &ProxyConfig{}},
+		{"fallback server", NewProxyConfig("127.0.0.1:1080", ""), NewProxyConfig("127.0.0.1:1080", "")},
+		{"fallback pac", NewPacConfig("http://127.0.0.1/proxy.pac"), NewPacConfig("http://127.0.0.1/proxy.pac")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := NewProxyConfig("127.0.0.1:7890", "localhost")
+			b := &memoryBackend{config: proxy}
+			probe := &fakeProbe{}
+			states := launchWatchdog(t, &Watchdog{
+				Proxy:     proxy,
+				Fallback:  tt.fallback,
+				Failures:  3,
+				ProbeFunc: probe.probe,
+				Backend:   b,
+			})
+
+			if s := nextState(t, states); s.State != WatchdogFailing || s.Failures != 1 || s.Endpoint != "127.0.0.1:7890" {
+				t.Errorf("first failure = %+v, want failing with 1 failure", s)
+			}
+			if s := nextState(t, states); s.State != WatchdogTripped || s.Failures != 3 {
+				t.Errorf("after threshold = %+v, want tripped with 3 failures", s)
+			}
+			if !b.current().Equal(tt.want) {
+				t.Errorf("fallback applied %+v, want %+v", b.current(), tt.want)
+			}
+
+			probe.healthy.Store(true)
+			if s := nextState(t, states); s.State != WatchdogHealthy || s.Failures != 0 {
+				t.Errorf("after recovery = %+v, want healthy", s)
+			}
+			if applies, restores := b.counts(); applies != 1 || restores != 1 || !b.current().Equal(proxy) {
+				t.Errorf("applies = %d, restores = %d, settings = %+v, want proxy restored once", applies, restores, b.current())
+			}
+		})
+	}
+}
+
+func TestWatchdogFallbackFails(t *testing.T) {
+	proxy := NewProxyConfig("127.0.0.1:7890", "")
+	b := &memoryBackend{config: proxy, err: errors.New("apply failed")}
+	probe := &fakeProbe{}
+	watchdog := &Watchdog{Proxy: proxy, Failures: 2, ProbeFunc: probe.probe, Backend: b}
+	states := launchWatchdog(t, watchdog)
+
+	if s := nextState(t, states); s.State != WatchdogFailing {
+		t.Fatalf("state = %+v, want failing", s)
+	}
+	// 切换失败时保持 failing 并在之后的每次探测重试
+	deadline := time.Now().Add(time.Second)
+	for watchdog.Status().Failures < 4 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if s := watchdog.Status(); s.State != WatchdogFailing || s.LastError != "apply failed" {
+		t.Errorf("status = %+v, want failing with apply error", s)
+	}
+
+	b.setErr(nil)
+	if s := nextState(t, states); s.State != WatchdogTripped {
+		t.Errorf("state = %+v, want tripped after apply succeeds", s)
+	}
+}
+
+func TestWatchdogValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		watchdog *Watchdog
+		valid    bool
+	}{
+		{"proxy server", &Watchdog{Proxy: NewProxyConfig("127.0.0.1:7890", "")}, true},
+		{"server with scheme", &Watchdog{Proxy: NewProxyConfig("socks5://127.0.0.1:1080", ""), Probe: "socks5"}, true},
+		{"endpoint", &Watchdog{Proxy: &ProxyConfig{}, Endpoint: "127.0.0.1:80", Probe: "http"}, true},
+		{"no port", &Watchdog{Proxy: NewProxyConfig("127.0.0.1", "")}, false},
+		{"no proxy", &Watchdog{Proxy: &ProxyConfig{}}, false},
+		{"unknown probe", &Watchdog{Proxy: NewProxyConfig("127.0.0.1:7890", ""), Probe: "icmp"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.watchdog.Validate()
+			if (err == nil) != tt.valid {
+				t.Errorf("Validate() = %v, want valid %v", err, tt.valid)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidInput) {
+				t.Errorf("Validate() = %v, want ErrInvalidInput", err)
+			}
+		})
+	}
+}
+
+// serveReply 在本地监听，对每个连接读取 read 字节后回复 reply 并关闭，read 为 -1 时返回未监听的地址
+func serveReply(t *testing.T, read int, reply []byte) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read < 0 {
+		l.Close()
+		return l.Addr().String()
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = io.ReadFull(conn, make([]byte, read))
+			_, _ = conn.Write(reply)
+			conn.Close()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestProbes(t *testing.T) {
+	httpRequest := len("OPTIONS * HTTP/1.0\r\n\r\n")
+	tests := []struct {
+		name  string
+		probe string
+		// read 为服务端回复前读取的字节数，为 -1 时端口未监听
+		read  int
+		reply []byte
+		ok    bool
+	}{
+		{"tcp", "tcp", 0, nil, true},
+		{"tcp closed", "tcp", -1, nil, false},
+		{"http", "http", httpRequest, []byte("HTTP/1.1 400 Bad Request\r\n\r\n"), true},
+		{"http garbage", "http", httpRequest, []byte("SSH-2.0-OpenSSH\r\n"), false},
+		{"http no reply", "http", httpRequest, nil, false},
+		{"socks5", "socks5", 3, []byte{0x05, 0x00}, true},
+		{"socks5 no acceptable method", "socks5", 3, []byte{0x05, 0xFF}, false},
+		{"socks4 reply", "socks5", 3, []byte{0x04, 0x00}, false},
+		{"socks5 short reply", "socks5", 3, []byte{0x05}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			probe, err := probeFunc(tt.probe)
+			if err != nil {
+				t.Fatal(err)
+			}
+			endpoint := serveReply(t, tt.read, tt.reply)
+			err = probe(context.Background(), endpoint, time.Second)
+			if (err == nil) != tt.ok {
+				t.Errorf("probe = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/xishang0128/sysproxy-go/sysproxy"
+)
+
+var (
+	traceFormat string
+	traceFile   string
+)
+
+type traceRecord struct {
+	sysproxy.TraceEvent
+	Offset     string  `json:"offset"`
+	Duration   string  `json:"duration"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// setupTrace 启用调用跟踪，timeline 格式输出便于阅读的时间线，json 格式每次调用输出一行
+func setupTrace() error {
+	format := traceFormat
+	if format == "" {
+		if traceFile == "" {
+			return nil
+		}
+		format = "json"
+	}
+	if format != "timeline" && format != "json" {
+		return invalid("未知的跟踪格式：%s", format)
+	}
+
+	var w io.Writer = os.Stderr
+	if traceFile != "" {
+		f, err := os.OpenFile(traceFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+		if err != nil {
+			return fail("打开跟踪文件失败", err)
+		}
+		w = f
+	}
+
+	var mu sync.Mutex
+	sysproxy.SetTraceHook(func(e sysproxy.TraceEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		if format == "json" {
+			_ = json.NewEncoder(w).Encode(traceRecord{
+				TraceEvent: e,
+				Offset:     e.Start.Sub(processStart).String(),
+				Duration:   e.Duration.String(),
+				DurationMs: float64(e.Duration.Microseconds()) / 1000,
+			})
+			return
+		}
+		writeTimeline(w, e)
+	})
+	return nil
+}
+
+func writeTimeline(w io.Writer, e sysproxy.TraceEvent) {
+	offset := float64(e.Start.Sub(processStart).Microseconds()) / 1000
+	duration := float64(e.Duration.Microseconds()) / 1000
+	fmt.Fprintf(w, "+%9.1fms %9.1fms  %-7s %s", offset, duration, e.Kind, e.Name)
+	for _, arg := range e.Args {
+		fmt.Fprint(w, " ", quoteArg(arg))
+	}
+	fmt.Fprintf(w, "  => %d", e.ExitCode)
+	if e.Error != "" {
+		fmt.Fprintf(w, " (%s)", e.Error)
+	}
+	fmt.Fprintln(w)
+	if e.Stderr != "" {
+		for line := range strings.Lines(e.Stderr) {
+			fmt.Fprintf(w, "%26s| %s\n", "", strings.TrimRight(line, "\n"))
+		}
+	}
+}
+
+func quoteArg(arg string) string {
+	if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\") {
+		return strconv.Quote(arg)
+	}
+	return arg
+}
+
+func init() {
+	cmd.PersistentFlags().StringVar(&traceFormat, "trace", "", "跟踪外部命令与系统接口调用：timeline、json，仅指定 --trace 时为 timeline")
+	cmd.PersistentFlags().Lookup("trace").NoOptDefVal = "timeline"
+	cmd.PersistentFlags().StringVar(&traceFile, "trace-file", "", "将跟踪写入文件，默认输出到标准错误")
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/xishang0128/sysproxy-go/sysproxy"
+
+	"github.com/spf13/cobra"
+)
+
+var watchInterval time.Duration
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "监听代理设置变化，以 JSON 行输出",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		encoder := json.NewEncoder(os.Stdout)
+		events := 0
+		opts := sysproxy.WatchOptions{Device: device, OnlyActiveDevice: onlyActiveDevice, Interval: watchInterval}
+		for event := range sysproxy.WatchWith(ctx, opts) {
+			if err := encoder.Encode(event); err != nil {
+				return fail("输出事件失败", err)
+			}
+			events++
+		}
+		return done(cmd, "", map[string]int{"events": events})
+	},
+}
+
+func init() {
+	cmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().DurationVarP(&watchInterval, "interval", "i", sysproxy.DefaultWatchInterval, "无变更通知时的轮询间隔")
+}
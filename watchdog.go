@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/xishang0128/sysproxy-go/i18n"
+	"github.com/xishang0128/sysproxy-go/sysproxy"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchdogProbe          string
+	watchdogInterval       time.Duration
+	watchdogTimeout        time.Duration
+	watchdogFailures       int
+	watchdogFallbackServer string
+	watchdogFallbackUrl    string
+)
+
+var watchdogCmd = &cobra.Command{
+	Use:   "watchdog",
+	Short: "代理端点失效时切换到直连，恢复后重新设置代理",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		proxyConfig := sysproxy.NewProxyConfig(server, bypass)
+		if server == "" {
+			current, err := sysproxy.QueryProxySettings(device, onlyActiveDevice)
+			if err != nil {
+				return fail("查询代理设置失败", err)
+			}
+			if current.Mode() != sysproxy.ModeProxy {
+				return invalid("当前未设置代理，请使用 --server 指定")
+			}
+			proxyConfig = current
+		}
+
+		var fallback *sysproxy.ProxyConfig
+		switch {
+		case watchdogFallbackUrl != "":
+			fallback = sysproxy.NewPacConfig(watchdogFallbackUrl)
+		case watchdogFallbackServer != "":
+			fallback = sysproxy.NewProxyConfig(watchdogFallbackServer, bypass)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		watchdog := &sysproxy.Watchdog{
+			Proxy:            proxyConfig,
+			Fallback:         fallback,
+			Device:           device,
+			OnlyActiveDevice: onlyActiveDevice,
+			Probe:            watchdogProbe,
+			Interval:         watchdogInterval,
+			Timeout:          watchdogTimeout,
+			Failures:         watchdogFailures,
+			Backend:          auditedBackend{apply: "watchdog_fallback", restore: "watchdog_restore"},
+			OnStateChange: func(s sysproxy.WatchdogStatus) {
+				switch s.State {
+				case sysproxy.WatchdogTripped:
+					emit(i18n.Sprintf("代理端点无响应，已切换到备用设置：%s", s.LastError), s)
+				case sysproxy.WatchdogHealthy:
+					emit(i18n.Sprintf("代理端点已恢复：%s", s.Endpoint), s)
+				case sysproxy.WatchdogFailing:
+					emit(i18n.Sprintf("代理端点探测失败：%s", s.LastError), s)
+				}
+			},
+		}
+		// 在修改设置之前检查探测地址与探测方式
+		if err := watchdog.Validate(); err != nil {
+			return fail("启动看门狗失败", err)
+		}
+		if server != "" {
+			err := audit("watchdog", func() error {
+				return sysproxy.Apply(proxyConfig, device, onlyActiveDevice)
+			})
+			if err != nil {
+				return fail("设置代理失败", err)
+			}
+		}
+		if err := watchdog.Run(ctx); err != nil {
+			return fail("启动看门狗失败", err)
+		}
+		return done(cmd, "", watchdog.Status())
+	},
+}
+
+func init() {
+	cmd.AddCommand(watchdogCmd)
+
+	watchdogCmd.Flags().StringVarP(&server, "server", "s", "", "代理服务器地址，为空时使用当前设置")
+	watchdogCmd.Flags().StringVarP(&bypass, "bypass", "b", "", "绕过地址")
+	watchdogCmd.Flags().StringVar(&watchdogProbe, "probe", "tcp", "探测方式：tcp、http、socks5")
+	watchdogCmd.Flags().DurationVarP(&watchdogInterval, "interval", "i", 5*time.Second, "探测间隔")
+	watchdogCmd.Flags().DurationVar(&watchdogTimeout, "timeout", 2*time.Second, "单次探测超时")
+	watchdogCmd.Flags().IntVarP(&watchdogFailures, "failures", "n", 3, "连续失败多少次后切换")
+	watchdogCmd.Flags().StringVar(&watchdogFallbackServer, "fallback-server", "", "失效时使用的备用代理地址，默认直连")
+	watchdogCmd.Flags().StringVar(&watchdogFallbackUrl, "fallback-url", "", "失效时使用的备用 pac 地址")
+}